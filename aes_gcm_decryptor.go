@@ -0,0 +1,113 @@
+package pp_ioc
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "encoding/base64"
+    "github.com/pkg/errors"
+    "io"
+    "io/ioutil"
+    "os"
+    "strings"
+)
+
+// Length in bytes of the keys generated by GenerateEncryptionKey (AES-256)
+const EncryptionKeyLength = 32
+
+// Creates the AES-GCM decryptor. The key must be 16, 24 or 32 bytes long.
+// Cipher texts are expected to be base64-encoded nonce followed by the sealed data.
+func NewAesGcmDecryptor(key []byte) (PropertyDecryptor, error) {
+    aead, e := newAesGcm(key)
+    if e != nil {
+        return nil, e
+    }
+    return &aesGcmDecryptor{aead: aead}, nil
+}
+
+// Creates the AES-GCM decryptor with the base64-encoded key read from the file
+func NewAesGcmDecryptorFromFile(path string) (PropertyDecryptor, error) {
+    content, e := ioutil.ReadFile(path)
+    if e != nil {
+        return nil, errors.Wrap(e, "Cannot read encryption key file "+path)
+    }
+    key, e := decodeEncryptionKey(string(content))
+    if e != nil {
+        return nil, errors.Wrap(e, "Invalid encryption key in file "+path)
+    }
+    return NewAesGcmDecryptor(key)
+}
+
+// Creates the AES-GCM decryptor with the base64-encoded key read from the environment variable
+func NewAesGcmDecryptorFromEnv(variable string) (PropertyDecryptor, error) {
+    content, ok := os.LookupEnv(variable)
+    if !ok {
+        return nil, errors.New("Environment variable " + variable + " with encryption key is not set")
+    }
+    key, e := decodeEncryptionKey(content)
+    if e != nil {
+        return nil, errors.Wrap(e, "Invalid encryption key in environment variable "+variable)
+    }
+    return NewAesGcmDecryptor(key)
+}
+
+// Generates the random key and returns it base64-encoded,
+// in the format expected by NewAesGcmDecryptorFromFile and NewAesGcmDecryptorFromEnv
+func GenerateEncryptionKey() (string, error) {
+    key := make([]byte, EncryptionKeyLength)
+    if _, e := io.ReadFull(rand.Reader, key); e != nil {
+        return "", errors.Wrap(e, "Cannot generate encryption key")
+    }
+    return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypts the value with AES-GCM and returns it wrapped into ENC(...),
+// ready to be put into a property file
+func EncryptValue(key []byte, plainText string) (string, error) {
+    aead, e := newAesGcm(key)
+    if e != nil {
+        return "", e
+    }
+    nonce := make([]byte, aead.NonceSize())
+    if _, e := io.ReadFull(rand.Reader, nonce); e != nil {
+        return "", errors.Wrap(e, "Cannot generate nonce")
+    }
+    sealed := aead.Seal(nonce, nonce, []byte(plainText), nil)
+    return wrapEncryptedValue(base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+type aesGcmDecryptor struct {
+    aead cipher.AEAD
+}
+
+func (d *aesGcmDecryptor) Decrypt(cipherText string) (string, error) {
+    data, e := base64.StdEncoding.DecodeString(cipherText)
+    if e != nil {
+        return "", errors.Wrap(e, "Encrypted value is not valid base64")
+    }
+    nonceSize := d.aead.NonceSize()
+    if len(data) < nonceSize {
+        return "", errors.New("Encrypted value is too short")
+    }
+    plainText, e := d.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+    if e != nil {
+        return "", errors.Wrap(e, "Cannot decrypt value")
+    }
+    return string(plainText), nil
+}
+
+func newAesGcm(key []byte) (cipher.AEAD, error) {
+    block, e := aes.NewCipher(key)
+    if e != nil {
+        return nil, errors.Wrap(e, "Invalid encryption key")
+    }
+    aead, e := cipher.NewGCM(block)
+    if e != nil {
+        return nil, errors.Wrap(e, "Cannot create AES-GCM cipher")
+    }
+    return aead, nil
+}
+
+func decodeEncryptionKey(encoded string) ([]byte, error) {
+    return base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+}
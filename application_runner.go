@@ -0,0 +1,110 @@
+package pp_ioc
+
+import (
+    "context"
+    "github.com/pkg/errors"
+    log "github.com/sirupsen/logrus"
+    logCtx "github.com/wlad031/pp-logging"
+    "os"
+    "os/signal"
+    "sort"
+    "syscall"
+)
+
+const (
+    ExitCodeSuccess = 0
+    ExitCodeFailure = 1
+)
+
+// Beans implementing this interface are run by Run after the context is built,
+// in order of their priorities
+type ApplicationRunner interface {
+    Run(ctx context.Context, args []string) error
+}
+
+// Registers the configurations, builds and starts the context and runs all the
+// ApplicationRunner beans. Then waits for SIGINT/SIGTERM or the cancellation of ctx,
+// stops and closes the context.
+// Returns the exit code of the application.
+func Run(ctx context.Context, configurations ...Configuration) int {
+    logger := logCtx.Get("IOC.Run")
+    appCtx := NewContext().(*contextImpl)
+
+    runCtx, cancel := withShutdownSignals(ctx)
+    defer cancel()
+
+    if e := appCtx.Register(configurations...); e != nil {
+        logger.WithFields(log.Fields{"error": e}).Error("Cannot register the configurations")
+        return ExitCodeFailure
+    }
+    if e := appCtx.Build(); e != nil {
+        logger.WithFields(log.Fields{"error": e}).Error("Cannot build the context")
+        return ExitCodeFailure
+    }
+
+    if e := appCtx.Start(); e != nil {
+        logger.WithFields(log.Fields{"error": e}).Error("Cannot start the context")
+        _ = appCtx.Close()
+        return ExitCodeFailure
+    }
+
+    exitCode := ExitCodeSuccess
+    if e := appCtx.runApplicationRunners(runCtx, os.Args[1:]); e != nil {
+        logger.WithFields(log.Fields{"error": e}).Error("Application runner failed")
+        exitCode = ExitCodeFailure
+    } else {
+        logger.Info("Application started, waiting for the shutdown signal...")
+        <-runCtx.Done()
+    }
+
+    if e := appCtx.Stop(DefaultStopTimeout); e != nil {
+        logger.WithFields(log.Fields{"error": e}).Error("Cannot stop the context")
+        exitCode = ExitCodeFailure
+    }
+    if e := appCtx.Close(); e != nil {
+        logger.WithFields(log.Fields{"error": e}).Error("Cannot close the context")
+        exitCode = ExitCodeFailure
+    }
+    return exitCode
+}
+
+// Returns the context which is cancelled when the parent is done or SIGINT/SIGTERM is received
+func withShutdownSignals(parent context.Context) (context.Context, context.CancelFunc) {
+    ctx, cancel := context.WithCancel(parent)
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+    go func() {
+        select {
+        case <-signals:
+        case <-ctx.Done():
+        }
+        signal.Stop(signals)
+        cancel()
+    }()
+    return ctx, cancel
+}
+
+func (ctx *contextImpl) runApplicationRunners(runCtx context.Context, args []string) error {
+    res, e := ctx.snapshotBuilt("run application runners", nil)
+    if e != nil {
+        return e
+    }
+    var runners []*bean
+    for _, bean := range res.beans {
+        if bean.definition.isApplicationRunner() {
+            runners = append(runners, bean)
+        }
+    }
+    sort.SliceStable(runners, func(i, j int) bool {
+        return runners[i].definition.priority > runners[j].definition.priority
+    })
+    for _, runner := range runners {
+        ctx.logger.WithFields(log.Fields{
+            "beanDef": runner.definition.shortString(),
+        }).Info("Running application runner")
+        if e := runner.instance.(ApplicationRunner).Run(runCtx, args); e != nil {
+            return errors.Wrap(e, "Application runner "+runner.definition.shortString()+" failed")
+        }
+    }
+    return nil
+}
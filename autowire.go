@@ -0,0 +1,81 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+    "reflect"
+    "unsafe"
+)
+
+func (ctx *contextImpl) Autowire(target interface{}) error {
+    return ctx.autowire(target, false)
+}
+
+func (ctx *contextImpl) AutowireUnexported(target interface{}) error {
+    return ctx.autowire(target, true)
+}
+
+func (ctx *contextImpl) autowire(target interface{}, unexported bool) error {
+    res, e := ctx.snapshotBuilt("autowire", nil)
+    if e != nil {
+        return e
+    }
+    targetValue := reflect.ValueOf(target)
+    if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() ||
+        targetValue.Elem().Kind() != reflect.Struct {
+        return errors.New("Cannot autowire: non-nil struct pointer expected, got " +
+            reflect.TypeOf(target).String())
+    }
+    structValue := targetValue.Elem()
+    structType := structValue.Type()
+
+    errs := &MultiError{}
+    for i := 0; i < structType.NumField(); i++ {
+        structField := structType.Field(i)
+        if !isAutowiredField(structField) {
+            continue
+        }
+        isExported := structField.PkgPath == ""
+        if !isExported && !unexported {
+            continue
+        }
+        if _, isValue := structField.Tag.Lookup(TagValue); isValue {
+            if e := validateValue(structField); e != nil {
+                errs.append(errors.Wrap(e, "Cannot autowire field "+structField.Name))
+                continue
+            }
+        }
+
+        dependency := newFieldDependency(structField, uint16(i))
+        value, e := ctx.findDependencyValue(res, nil, dependency)
+        if e != nil {
+            errs.append(errors.Wrap(e, "Cannot autowire field "+structField.Name+
+                " of "+structType.String()))
+            continue
+        }
+
+        field := structValue.Field(i)
+        if !value.Type().AssignableTo(field.Type()) {
+            errs.append(errors.New("Cannot autowire field " + structField.Name + " of " +
+                structType.String() + ": " + value.Type().String() + " is not assignable to " +
+                field.Type().String()))
+            continue
+        }
+        if !isExported {
+            field = reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+        }
+        field.Set(value)
+    }
+    return errs.errorOrNil()
+}
+
+func isAutowiredField(structField reflect.StructField) bool {
+    if isQualifiedWrapper(structField.Type) {
+        return true
+    }
+    for _, tag := range []string{TagQualifier, TagValue, TagInject} {
+        if _, ok := structField.Tag.Lookup(tag); ok {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,49 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+    log "github.com/sirupsen/logrus"
+    logCtx "github.com/wlad031/pp-logging"
+    "reflect"
+)
+
+func warnDeprecatedAlias(logger logCtx.NamedLogger, alias string, definition *beanDefinition) {
+    logger.WithFields(log.Fields{
+        "alias":   alias,
+        "beanDef": definition.shortString(),
+    }).Warn("Bean is requested by the deprecated alias")
+}
+
+// Builds the slice or the map of all the beans matching the qualifiers,
+// map keys are the qualifiers matched by the pattern and must be unique
+func (ctx *contextImpl) collectDependencyBeans(res *resolution, dependency *dependency) (reflect.Value, error) {
+    var collection reflect.Value
+    if dependency.type_.Kind() == reflect.Map {
+        collection = reflect.MakeMap(dependency.type_)
+    } else {
+        collection = reflect.MakeSlice(dependency.type_, 0, 0)
+    }
+    pattern := dependency.collectionPattern()
+    keys := map[string]*beanDefinition{}
+    for _, definition := range res.index.find(dependency) {
+        if !res.hasBean(definition) {
+            continue
+        }
+        instance, e := ctx.getDefinitionInstance(res, definition)
+        if e != nil {
+            return reflect.Value{}, e
+        }
+        if dependency.type_.Kind() == reflect.Map {
+            key := definition.findQualifier(pattern)
+            if existing, ok := keys[key]; ok {
+                return reflect.Value{}, errors.New("Beans " + existing.shortString() + " and " +
+                    definition.shortString() + " have the same key " + key + " in " + dependency.String())
+            }
+            keys[key] = definition
+            collection.SetMapIndex(reflect.ValueOf(key).Convert(dependency.type_.Key()), reflect.ValueOf(instance))
+        } else {
+            collection = reflect.Append(collection, reflect.ValueOf(instance))
+        }
+    }
+    return collection, nil
+}
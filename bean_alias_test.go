@@ -0,0 +1,71 @@
+package pp_ioc
+
+import (
+    "strings"
+    "testing"
+)
+
+type testCache struct {
+    name string
+}
+
+type cachesConfiguration struct {
+    duplicate bool
+}
+
+func (c *cachesConfiguration) Bind(ctx Context) error {
+    ctx.NewBinder().
+        Qualifiers("primary", "cache.users").
+        Factory(func() *testCache {
+            return &testCache{name: "users"}
+        })
+    ctx.NewBinder().
+        Qualifiers("primary", "cache.orders").
+        Factory(func() *testCache {
+            return &testCache{name: "orders"}
+        })
+    if c.duplicate {
+        ctx.NewBinder().
+            Qualifiers("primary", "cache.orders", "orders").
+            Factory(func() *testCache {
+                return &testCache{name: "orders-copy"}
+            })
+    }
+    return nil
+}
+
+func injectCaches(t *testing.T, duplicate bool) (map[string]*testCache, error) {
+    t.Helper()
+    ctx := NewContext()
+    if e := ctx.Register(&cachesConfiguration{duplicate: duplicate}); e != nil {
+        t.Fatal(e)
+    }
+    if e := ctx.Build(); e != nil {
+        t.Fatal(e)
+    }
+    defer func() {
+        _ = ctx.Close()
+    }()
+    var target struct {
+        Caches map[string]*testCache `qualifier:"primary,cache.*"`
+    }
+    e := ctx.Autowire(&target)
+    return target.Caches, e
+}
+
+func TestCollectionMapIsKeyedByPattern(t *testing.T) {
+    caches, e := injectCaches(t, false)
+    if e != nil {
+        t.Fatal(e)
+    }
+    if len(caches) != 2 || caches["cache.users"].name != "users" || caches["cache.orders"].name != "orders" {
+        t.Errorf("Expected the caches keyed by the qualifiers matching the pattern, got %v", caches)
+    }
+}
+
+func TestCollectionMapRejectsDuplicateKeys(t *testing.T) {
+    _, e := injectCaches(t, true)
+    if e == nil || !strings.Contains(e.Error(), "have the same key cache.orders") {
+        t.Errorf("Expected the duplicate key error, got %v", e)
+    }
+}
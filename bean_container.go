@@ -6,31 +6,68 @@ import (
 )
 
 type beanContainer struct {
-    logger logCtx.NamedLogger
-    ls     []*bean
+    logger       logCtx.NamedLogger
+    ls           []*bean
+    byDefinition map[*beanDefinition]*bean
 }
 
 func newBeanContainer() *beanContainer {
     return &beanContainer{
-        logger: logCtx.Get("IOC.BeanContainer"),
-        ls:     []*bean{},
+        logger:       logCtx.Get("IOC.BeanContainer"),
+        ls:           []*bean{},
+        byDefinition: map[*beanDefinition]*bean{},
     }
 }
 
-func (container *beanContainer) iterate() <-chan *bean {
-    c := make(chan *bean)
-    go func() {
-        for _, v := range container.ls {
-            c <- v
-        }
-        close(c)
-    }()
-    return c
+// Returns the copy, which can be changed while the lookups use the snapshot of the original
+func (container *beanContainer) clone() *beanContainer {
+    res := newBeanContainer()
+    res.ls = append(res.ls, container.ls...)
+    for definition, b := range container.byDefinition {
+        res.byDefinition[definition] = b
+    }
+    return res
+}
+
+// Returns the underlying slice, callers must not modify it.
+// Adding beans never changes the elements the slice already has.
+func (container *beanContainer) all() []*bean {
+    return container.ls
 }
 
 func (container *beanContainer) add(b *bean) {
     container.ls = append(container.ls, b)
+    if _, ok := container.byDefinition[b.definition]; !ok {
+        container.byDefinition[b.definition] = b
+    }
     container.logger.WithFields(log.Fields{
         "beanDef": b.definition.String(),
+        "module":  b.definition.module,
     }).Info("Bean added")
 }
+
+// Replaces the bean created by the same definition and returns the replaced one,
+// if there was no such bean, adds it and returns nil. The slice and the map are copied,
+// so the snapshots taken by lookups are not modified.
+func (container *beanContainer) replace(b *bean) *bean {
+    for i, v := range container.ls {
+        if v.definition == b.definition {
+            ls := make([]*bean, len(container.ls))
+            copy(ls, container.ls)
+            ls[i] = b
+            container.ls = ls
+            byDefinition := make(map[*beanDefinition]*bean, len(container.byDefinition))
+            for definition, existing := range container.byDefinition {
+                byDefinition[definition] = existing
+            }
+            byDefinition[b.definition] = b
+            container.byDefinition = byDefinition
+            container.logger.WithFields(log.Fields{
+                "beanDef": b.definition.String(),
+            }).Info("Bean replaced")
+            return v
+        }
+    }
+    container.add(b)
+    return nil
+}
@@ -3,29 +3,47 @@ package pp_ioc
 import (
     "github.com/pkg/errors"
     ps "github.com/wlad031/pp-properties/property_source"
+    "path"
     "reflect"
+    "sort"
     "strings"
+    "sync"
 )
 
 type beanDefinition struct {
     key          *bindKey
+    aliases      []string // deprecated names, not a part of the key
     dependencies map[uint16]*dependency
     priority     int
     paramTypes   []reflect.Type
     scope        BeanScope
+    scopeName    string
+    customScope  Scope
     factory      *beanFactory
     graphIndex   int
+    overrides    bool
+    lazy         bool // singleton created on the first lookup or injection
+    module       string
+    decorators   []*beanDefinition // ordered from the innermost to the outermost
+    decorate     func(res *resolution, instance interface{}) (interface{}, error)
+    intercepts   []*dependency     // lookups of the beans the interceptor is applied to
+    interceptors []*beanDefinition // ordered from the outermost to the innermost
+    intercept    func(res *resolution, instance interface{}) (interface{}, error)
     _bean        *bean // Do not use it directly!
+    _beanMutex   sync.Mutex
 }
 
-func (bd *beanDefinition) createBean(params []reflect.Value) (*bean, error) {
+func (bd *beanDefinition) createBean(res *resolution, params []reflect.Value) (*bean, error) {
     switch bd.scope {
-    case ScopeSingleton:
+    case ScopeSingleton, ScopeRefresh:
         {
+            // the factory of the singleton is called only once
+            bd._beanMutex.Lock()
+            defer bd._beanMutex.Unlock()
             if bd._bean != nil {
                 return bd._bean, nil
             } else {
-                instance, e := bd.factory.call(params)
+                instance, e := bd.newInstance(res, params)
                 if e != nil {
                     return nil, e
                 }
@@ -36,9 +54,9 @@ func (bd *beanDefinition) createBean(params []reflect.Value) (*bean, error) {
                 return bd._bean, nil
             }
         }
-    case ScopePrototype:
+    case ScopePrototype, ScopeRequest:
         {
-            instance, e := bd.factory.call(params)
+            instance, e := bd.newInstance(res, params)
             if e != nil {
                 return nil, e
             }
@@ -52,23 +70,165 @@ func (bd *beanDefinition) createBean(params []reflect.Value) (*bean, error) {
     }
 }
 
+// Returns the lazy singleton, calling the factory on the first call only.
+// The params are resolved only if the bean is not created yet.
+func (bd *beanDefinition) getLazyBean(
+    res *resolution,
+    resolveParams func() ([]reflect.Value, error),
+) (*bean, error) {
+    bd._beanMutex.Lock()
+    defer bd._beanMutex.Unlock()
+    if bd._bean != nil {
+        return bd._bean, nil
+    }
+    params, e := resolveParams()
+    if e != nil {
+        return nil, e
+    }
+    instance, e := bd.newInstance(res, params)
+    if e != nil {
+        return nil, e
+    }
+    bd._bean = &bean{
+        definition: bd,
+        instance:   instance,
+    }
+    return bd._bean, nil
+}
+
+// Returns the instance from the custom scope.
+// The params are resolved only if the scope does not have the instance yet.
+func (bd *beanDefinition) getScopedInstance(
+    res *resolution,
+    resolveParams func() ([]reflect.Value, error),
+) (interface{}, error) {
+    return bd.customScope.Get(bd.key.String(), func() (interface{}, error) {
+        params, e := resolveParams()
+        if e != nil {
+            return nil, e
+        }
+        return bd.newInstance(res, params)
+    })
+}
+
+// Calls the factory and wraps the instance by the decorators and the interceptors proxy
+func (bd *beanDefinition) newInstance(res *resolution, params []reflect.Value) (interface{}, error) {
+    instance, e := bd.factory.call(params)
+    if e != nil {
+        return nil, &FactoryError{Definition: bd.shortString(), Err: e}
+    }
+    if bd.decorate != nil {
+        if instance, e = bd.decorate(res, instance); e != nil {
+            return nil, e
+        }
+    }
+    if bd.intercept != nil {
+        return bd.intercept(res, instance)
+    }
+    return instance, nil
+}
+
+// Name of the scope, custom scopes are named as registered
+func (bd *beanDefinition) scopeString() string {
+    if bd.scope == ScopeCustom {
+        return bd.scopeName
+    }
+    return bd.scope.String()
+}
+
+// Prototype, request and custom scoped beans, as well as lazy singletons,
+// are not instantiated by Build. Their instances are created or taken from the scope
+// on lookup and injection.
+func (bd *beanDefinition) isCreatedOnLookup() bool {
+    return bd.lazy ||
+        bd.scope == ScopePrototype ||
+        bd.scope == ScopeRequest ||
+        bd.scope == ScopeCustom
+}
+
+// Forgets the cached instance, so the next call of createBean will call the factory again,
+// and returns the forgotten bean or nil. The custom scoped instance is removed from its scope.
+func (bd *beanDefinition) reset() *bean {
+    if bd.scope == ScopeCustom {
+        bd.customScope.Remove(bd.key.String())
+    }
+    bd._beanMutex.Lock()
+    defer bd._beanMutex.Unlock()
+    b := bd._bean
+    bd._bean = nil
+    return b
+}
+
+// Checks whether the definition injects at least one of the given properties
+func (bd *beanDefinition) injectsAnyProperty(keys map[string]bool) bool {
+    for _, dependency := range bd.allDependencies() {
+        if dependency.isValue && keys[dependency.valueProvider.qualifier] {
+            return true
+        }
+    }
+    return false
+}
+
+// All the qualifiers of the dependency must match the qualifiers or aliases of the definition,
+// as well as all the markers
 func (bd *beanDefinition) isSuitableForDependencyByQualifier(dependency *dependency) bool {
-    var isSuitableByName = false
+    for _, qualifier := range dependency.qualifiers {
+        if !bd.matchesQualifier(qualifier) {
+            return false
+        }
+    }
+    for _, marker := range dependency.markers {
+        if !bd.key.hasMarker(marker) {
+            return false
+        }
+    }
+    return dependency.isQualified()
+}
+
+// Patterns are matched against the qualifiers only, not aliases
+func (bd *beanDefinition) matchesQualifier(qualifier string) bool {
+    if isQualifierPattern(qualifier) {
+        return bd.findQualifier(qualifier) != ""
+    }
+    return bd.key.hasQualifier(qualifier) || bd.isAlias(qualifier)
+}
+
+// Returns the first qualifier matching the pattern or an empty string
+func (bd *beanDefinition) findQualifier(pattern string) string {
     for _, name := range bd.key.qualifiers {
-        if name == dependency.qualifier {
-            isSuitableByName = true
-            break
+        if matched, e := path.Match(pattern, name); e == nil && matched {
+            return name
+        }
+    }
+    return ""
+}
+
+func (bd *beanDefinition) isAlias(name string) bool {
+    for _, alias := range bd.aliases {
+        if alias == name {
+            return true
         }
     }
-    if !isSuitableByName {
-        return false
+    return false
+}
+
+// Returns the qualifiers of the dependency which match only the deprecated aliases
+func (bd *beanDefinition) aliasesUsedBy(dependency *dependency) []string {
+    var res []string
+    for _, qualifier := range dependency.qualifiers {
+        if bd.isAlias(qualifier) {
+            res = append(res, qualifier)
+        }
     }
-    return true
+    return res
+}
+
+func (bd *beanDefinition) isSuitableForDependencyByType(dependency *dependency) bool {
+    return bd.isSuitableForType(dependency.beanType())
 }
 
 // TODO: refactor this function
-func (bd *beanDefinition, ) isSuitableForDependencyByType(dependency *dependency) bool {
-    dependencyType := dependency.type_
+func (bd *beanDefinition) isSuitableForType(dependencyType reflect.Type) bool {
     if dependencyType.Kind() == reflect.Ptr {
         dependencyType = dependencyType.Elem()
     }
@@ -92,7 +252,9 @@ func (bd *beanDefinition, ) isSuitableForDependencyByType(dependency *dependency
         }
         if bd.key.type_.Kind() == reflect.Ptr {
             if bd.key.type_.Elem().Kind() == reflect.Struct {
-                return bd.key.type_.Elem().Implements(dependencyType)
+                // Method set of the pointer includes the methods with pointer receivers,
+                // so *T suits the interfaces which T alone does not implement
+                return bd.key.type_.Implements(dependencyType)
             }
             if bd.key.type_.Elem().Kind() == reflect.Interface { // TODO: may not work correctly
                 return bd.key.type_.Elem().Implements(dependencyType)
@@ -111,10 +273,51 @@ func (bd *beanDefinition) isPropertySource() bool {
     return bd.key.type_.Implements(reflect.TypeOf((*ps.PropertySource)(nil)).Elem())
 }
 
+func (bd *beanDefinition) isPropertyDecryptor() bool {
+    return bd.key.type_.Implements(reflect.TypeOf((*PropertyDecryptor)(nil)).Elem())
+}
+
+// Context, Environment, property sources and decryptors are instantiated before other beans
+func (bd *beanDefinition) isInfrastructure() bool {
+    return bd.key.type_ == reflect.TypeOf((*Context)(nil)).Elem() ||
+        bd.key.type_ == reflect.TypeOf((*Environment)(nil)).Elem() ||
+        bd.isPropertySource() ||
+        bd.isPropertyDecryptor()
+}
+
 func (bd *beanDefinition) isPostProcessor() bool {
     return bd.key.type_.Implements(reflect.TypeOf((*PostProcessor)(nil)).Elem())
 }
 
+func (bd *beanDefinition) isApplicationRunner() bool {
+    return bd.key.type_.Implements(reflect.TypeOf((*ApplicationRunner)(nil)).Elem())
+}
+
+func (bd *beanDefinition) isLifecycle() bool {
+    return bd.key.type_.Implements(reflect.TypeOf((*Lifecycle)(nil)).Elem())
+}
+
+// Returns the dependencies ordered by their indexes
+func (bd *beanDefinition) sortedDependencies() []*dependency {
+    res := make([]*dependency, 0, len(bd.dependencies))
+    for _, dependency := range bd.dependencies {
+        res = append(res, dependency)
+    }
+    sort.Slice(res, func(i, j int) bool {
+        return res[i].index < res[j].index
+    })
+    return res
+}
+
+// Returns the dependencies of the definition followed by the ones of its decorators
+func (bd *beanDefinition) allDependencies() []*dependency {
+    res := bd.sortedDependencies()
+    for _, decorator := range bd.decorators {
+        res = append(res, decorator.sortedDependencies()...)
+    }
+    return res
+}
+
 func (bd *beanDefinition) shortString() string {
     return "BeanDef{" + bd.key.String() + "}"
 }
@@ -125,6 +328,6 @@ func (bd *beanDefinition) String() string {
         depStrings = append(depStrings, dep.String())
     }
     return "BeanDef{" + bd.key.String() + ":" +
-        bd.scope.String() + ":[" +
+        bd.scopeString() + ":[" +
         strings.Join(depStrings, ",") + "]}"
 }
\ No newline at end of file
@@ -1,33 +1,87 @@
 package pp_ioc
 
+import (
+    "reflect"
+    "sort"
+    "strings"
+)
+
 type beanDefinitionContainer struct {
     ls []*beanDefinition
+    // Definitions by type and qualifiers, so duplicates are found without scanning all of them
+    byKey map[definitionKey][]*beanDefinition
 }
 
-func newBeanDefinitionList() *beanDefinitionContainer {
-    return &beanDefinitionContainer{ls: []*beanDefinition{}}
+// Type and the sorted distinct qualifiers of the definition,
+// definitions with the same key may still differ by markers
+type definitionKey struct {
+    type_      reflect.Type
+    qualifiers string
 }
 
-func (container *beanDefinitionContainer) iterate() <-chan *beanDefinition {
-    c := make(chan *beanDefinition)
-    go func() {
-        for _, v := range container.ls {
-            c <- v
+func newDefinitionKey(key *bindKey) definitionKey {
+    set := map[string]bool{}
+    var qualifiers []string
+    for _, qualifier := range key.qualifiers {
+        if !set[qualifier] {
+            set[qualifier] = true
+            qualifiers = append(qualifiers, qualifier)
         }
-        close(c)
-    }()
-    return c
+    }
+    sort.Strings(qualifiers)
+    return definitionKey{
+        type_:      key.type_,
+        qualifiers: strings.Join(qualifiers, "\x00"),
+    }
+}
+
+func newBeanDefinitionList() *beanDefinitionContainer {
+    return &beanDefinitionContainer{
+        ls:    []*beanDefinition{},
+        byKey: map[definitionKey][]*beanDefinition{},
+    }
+}
+
+// Returns the underlying slice, callers must not modify it
+func (container *beanDefinitionContainer) all() []*beanDefinition {
+    return container.ls
 }
 
+// Keeps the definitions ordered by descending priority, equal ones in the order of adding
 func (container *beanDefinitionContainer) add(bd *beanDefinition) {
-    // FIXME: performance is not good because of slice copying
-    container.ls = append(container.ls, bd)
-    for i := 0; i < len(container.ls); i++ {
-        cur := container.ls[i]
-        if cur.priority < bd.priority {
-            copy(container.ls[i+1:], container.ls[i:])
-            container.ls[i] = bd
+    i := sort.Search(len(container.ls), func(i int) bool {
+        return container.ls[i].priority < bd.priority
+    })
+    container.ls = append(container.ls, nil)
+    copy(container.ls[i+1:], container.ls[i:])
+    container.ls[i] = bd
+    key := newDefinitionKey(bd.key)
+    container.byKey[key] = append(container.byKey[key], bd)
+}
+
+func (container *beanDefinitionContainer) remove(bd *beanDefinition) {
+    for i, v := range container.ls {
+        if v == bd {
+            container.ls = append(container.ls[:i], container.ls[i+1:]...)
             break
         }
     }
+    key := newDefinitionKey(bd.key)
+    sameKey := container.byKey[key]
+    for i, v := range sameKey {
+        if v == bd {
+            container.byKey[key] = append(sameKey[:i:i], sameKey[i+1:]...)
+            return
+        }
+    }
+}
+
+// Returns the definition with the same key, see isSameDefinitionKey
+func (container *beanDefinitionContainer) findSameKey(bd *beanDefinition) *beanDefinition {
+    for _, v := range container.byKey[newDefinitionKey(bd.key)] {
+        if isSameDefinitionKey(v, bd) {
+            return v
+        }
+    }
+    return nil
 }
@@ -0,0 +1,103 @@
+package pp_ioc
+
+import (
+    "reflect"
+    "testing"
+)
+
+type testGreeter interface {
+    Greet() string
+}
+
+// Implements testGreeter only with the pointer receiver
+type testEnglishGreeter struct{}
+
+func (g *testEnglishGreeter) Greet() string {
+    return "hello"
+}
+
+type testGermanGreeter struct{}
+
+func (g *testGermanGreeter) Greet() string {
+    return "hallo"
+}
+
+type testGreeting struct {
+    text string
+}
+
+func bindGreeting(ctx Context) {
+    ctx.NewBinder().
+        Qualifiers("greeting").
+        Factory(func(p struct {
+            Greeter testGreeter
+        }) *testGreeting {
+            return &testGreeting{text: p.Greeter.Greet()}
+        })
+}
+
+func TestPointerBeanSuitsInterfaceOfPointerReceivers(t *testing.T) {
+    ctx := NewContext()
+    ctx.NewBinder().
+        Qualifiers("englishGreeter").
+        Factory(func() *testEnglishGreeter {
+            return &testEnglishGreeter{}
+        })
+    bindGreeting(ctx)
+    if e := ctx.Build(); e != nil {
+        t.Fatal(e)
+    }
+    defer func() {
+        _ = ctx.Close()
+    }()
+    greeting, e := ctx.GetBeanByName("greeting")
+    if e != nil {
+        t.Fatal(e)
+    }
+    if text := greeting.(*testGreeting).text; text != "hello" {
+        t.Errorf("Expected hello, got %s", text)
+    }
+    greeter, e := ctx.GetBeanByType(reflect.TypeOf((*testGreeter)(nil)).Elem())
+    if e != nil {
+        t.Fatal(e)
+    }
+    if _, ok := greeter.(*testEnglishGreeter); !ok {
+        t.Errorf("Expected the english greeter, got %T", greeter)
+    }
+}
+
+func TestPointerBeansImplementingSameInterfaceAreAmbiguous(t *testing.T) {
+    ctx := NewContext()
+    ctx.NewBinder().
+        Qualifiers("englishGreeter").
+        Factory(func() *testEnglishGreeter {
+            return &testEnglishGreeter{}
+        })
+    ctx.NewBinder().
+        Qualifiers("germanGreeter").
+        Factory(func() *testGermanGreeter {
+            return &testGermanGreeter{}
+        })
+    bindGreeting(ctx)
+    e := ctx.Build()
+    if e == nil {
+        _ = ctx.Close()
+        t.Fatal("Expected the ambiguous greeter")
+    }
+    if !containsAmbiguousBeanError(e) {
+        t.Errorf("Expected AmbiguousBeanError, got %v", e)
+    }
+}
+
+func containsAmbiguousBeanError(e error) bool {
+    if multiError, ok := e.(*MultiError); ok {
+        for _, err := range multiError.Errors {
+            if containsAmbiguousBeanError(err) {
+                return true
+            }
+        }
+        return false
+    }
+    _, ok := e.(*AmbiguousBeanError)
+    return ok
+}
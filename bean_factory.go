@@ -1,7 +1,6 @@
 package pp_ioc
 
 import (
-    "github.com/pkg/errors"
     "reflect"
     "strings"
 )
@@ -30,8 +29,7 @@ func (bf *beanFactory) call(params []reflect.Value) (interface{}, error) {
     if len(factoryCallResult) > 1 {
         factoryError := factoryCallResult[1].Interface()
         if factoryError != nil {
-            return nil, errors.Wrap(factoryError.(error),
-                "Error happened during calling bean factory")
+            return nil, factoryError.(error)
         }
     }
     instance := factoryCallResult[0].Interface()
@@ -55,7 +53,12 @@ func (bf *beanFactory) collectDependencies() (
     for i := 0; i < len(bf._inParamTypes); i++ {
         paramType := bf._inParamTypes[i]
 
-        if (i == 0 && bf.isMethod) ||
+        if isQualifiedWrapper(paramType) {
+            dependencies[paramIndex] = newQualifiedDependency("", "", false, paramType, paramIndex)
+            dependencies[paramIndex].paramIndex = uint16(i)
+            paramTypes = append(paramTypes, paramType)
+            paramIndex += 1
+        } else if (i == 0 && bf.isMethod) ||
             (paramType.Kind() != reflect.Struct) ||
             (paramType.Kind() == reflect.Ptr && paramType.Elem().Kind() != reflect.Struct) {
             dependencies[paramIndex] = newBeanDependency(
@@ -63,6 +66,7 @@ func (bf *beanFactory) collectDependencies() (
                 "", false,
                 paramType,
                 paramIndex)
+            dependencies[paramIndex].paramIndex = uint16(i)
             paramTypes = append(paramTypes, paramType)
             paramIndex += 1
         } else {
@@ -72,28 +76,8 @@ func (bf *beanFactory) collectDependencies() (
             }
 
             for j := 0; j < structType.NumField(); j++ {
-                structField := structType.Field(j)
-                if qualifierTag, ok := structField.Tag.Lookup(TagQualifier); ok {
-                    dependencies[paramIndex] = newBeanDependency(
-                        structField.Name,
-                        qualifierTag, true,
-                        structField.Type,
-                        paramIndex)
-                } else if valueTag, ok := structField.Tag.Lookup(TagValue); ok {
-                    provider := parseValueTag(valueTag)
-                    dependencies[paramIndex] = newValueDependency(
-                        structField.Name,
-                        provider.qualifier, true,
-                        provider,
-                        structField.Type,
-                        paramIndex)
-                } else {
-                    dependencies[paramIndex] = newBeanDependency(
-                        structField.Name,
-                        "", false,
-                        structField.Type,
-                        paramIndex)
-                }
+                dependencies[paramIndex] = newFieldDependency(structType.Field(j), paramIndex)
+                dependencies[paramIndex].paramIndex = uint16(i)
                 paramIndex += 1
             }
 
@@ -104,6 +88,46 @@ func (bf *beanFactory) collectDependencies() (
     return dependencies, paramTypes
 }
 
+// Creates the dependency for the struct field according to its tags
+func newFieldDependency(structField reflect.StructField, index uint16) *dependency {
+    if isQualifiedWrapper(structField.Type) {
+        qualifier, hasQualifier := structField.Tag.Lookup(TagQualifier)
+        if !hasQualifier {
+            qualifier = structField.Tag.Get(TagInject)
+            hasQualifier = qualifier != ""
+        }
+        return newQualifiedDependency(structField.Name, qualifier, hasQualifier, structField.Type, index)
+    }
+    if qualifierTag, ok := structField.Tag.Lookup(TagQualifier); ok {
+        return newBeanDependency(
+            structField.Name,
+            qualifierTag, true,
+            structField.Type,
+            index)
+    }
+    if valueTag, ok := structField.Tag.Lookup(TagValue); ok {
+        provider := parseValueTag(valueTag)
+        dependency := newValueDependency(
+            structField.Name,
+            provider.qualifier, true,
+            provider,
+            structField.Type,
+            index)
+        if validateTag, ok := structField.Tag.Lookup(TagValidate); ok {
+            // the tag is already checked by beanFactoryValidator
+            dependency.constraints, _ = parseConstraints(validateTag)
+        }
+        dependency.description = structField.Tag.Get(TagDescription)
+        return dependency
+    }
+    injectTag := structField.Tag.Get(TagInject)
+    return newBeanDependency(
+        structField.Name,
+        injectTag, injectTag != "",
+        structField.Type,
+        index)
+}
+
 const (
     ValueTagSep    = ":"
     ValueTagPrefix = "${"
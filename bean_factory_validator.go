@@ -18,6 +18,37 @@ func newBeanFactoryValidator() *beanFactoryValidator {
 }
 
 func (v *beanFactoryValidator) validate(beanFactory *beanFactory) error {
+    if e := v.validateIn(beanFactory); e != nil {
+        return e
+    }
+    numOut := len(beanFactory._outParamTypes)
+    if numOut != 1 && numOut != 2 {
+        return errors.New("Invalid factory function: " +
+            "invalid number of OUT parameters (must be 1 or 2)")
+    }
+    outParamType := beanFactory._outParamTypes[0]
+    if outParamType.Kind() != reflect.Interface &&
+        outParamType.Kind() != reflect.Struct &&
+        !(outParamType.Kind() == reflect.Ptr &&
+            outParamType.Elem().Kind() != reflect.Interface &&
+            outParamType/*FIXME: .Elem()*/.Kind() != reflect.Struct) {
+        return errors.New("Invalid factory function: invalid type (" +
+            outParamType.Kind().String() +
+            ") of the first OUT parameters " +
+            "(must be interface/struct or *interface/*struct)")
+    }
+    if numOut == 2 {
+        if beanFactory.type_.Out(1).Kind() != reflect.Interface ||
+            !beanFactory.type_.Out(1).Implements(reflect.TypeOf((*error)(nil)).Elem()) {
+            return errors.New("Invalid factory function: invalid type of " +
+                "the second OUT parameter (must implement 'error')")
+        }
+    }
+    return nil
+}
+
+// Checks the function and its IN parameters, collecting the parameter types
+func (v *beanFactoryValidator) validateIn(beanFactory *beanFactory) error {
     if beanFactory.factoryFunction == nil {
         return errors.New("Invalid factory function: nil")
     }
@@ -33,7 +64,10 @@ func (v *beanFactoryValidator) validate(beanFactory *beanFactory) error {
         beanFactory._outParamTypes = append(beanFactory._outParamTypes, beanFactory.type_.Out(i))
     }
 
-    for _, paramType := range beanFactory._inParamTypes {
+    for paramIndex, paramType := range beanFactory._inParamTypes {
+        if paramIndex == 0 && beanFactory.isMethod {
+            continue // the receiver is injected as a bean
+        }
         if paramType.Kind() != reflect.Struct &&
             paramType.Kind() != reflect.Interface &&
             paramType.Kind() != reflect.Ptr &&
@@ -43,36 +77,35 @@ func (v *beanFactoryValidator) validate(beanFactory *beanFactory) error {
             return errors.New("Invalid factory function: only struct/interface or " +
                 "*struct/*interface IN params allowed")
         }
-        if paramType.Kind() == reflect.Struct ||
-            (paramType.Kind() == reflect.Ptr && paramType.Elem().Kind() == reflect.Struct) {
-            if paramType.Kind() == reflect.Ptr {
-                paramType = paramType.Elem()
+        if isQualifiedWrapper(paramType) {
+            if e := validateQualifiedWrapper(paramType); e != nil {
+                return errors.Wrap(e, "Invalid factory function")
             }
+            continue
+        }
+        // only struct params are expanded into dependencies, pointers are beans themselves
+        if paramType.Kind() == reflect.Struct {
             for i := 0; i < paramType.NumField(); i++ {
                 fieldType := paramType.Field(i)
                 switch fieldType.Type.Kind() {
 
-                case reflect.Bool:
-                case reflect.Int:
-                case reflect.Int8:
-                case reflect.Int16:
-                case reflect.Int32:
-                case reflect.Int64:
-                case reflect.Uint:
-                case reflect.Uint8:
-                case reflect.Uint16:
-                case reflect.Uint32:
-                case reflect.Uint64:
-                case reflect.Float32:
-                case reflect.Float64:
-                case reflect.String:
+                case reflect.Bool,
+                    reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+                    reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+                    reflect.Float32, reflect.Float64,
+                    reflect.String:
                     if e := validateValue(fieldType); e != nil {
                         return errors.Wrap(e, "Invalid value field")
                     }
                     continue
+                case reflect.Struct:
+                    if isQualifiedWrapper(fieldType.Type) {
+                        if e := validateQualifiedWrapper(fieldType.Type); e != nil {
+                            return errors.Wrap(e, "Invalid factory function")
+                        }
+                    }
                 case reflect.Uintptr:
                 case reflect.Interface:
-                case reflect.Struct:
                 case reflect.Ptr:
                 default:
                     continue
@@ -82,40 +115,28 @@ func (v *beanFactoryValidator) validate(beanFactory *beanFactory) error {
             }
         }
     }
-    numOut := len(beanFactory._outParamTypes)
-    if numOut != 1 && numOut != 2 {
-        return errors.New("Invalid factory function: " +
-            "invalid number of OUT parameters (must be 1 or 2)")
-    }
-    outParamType := beanFactory._outParamTypes[0]
-    if outParamType.Kind() != reflect.Interface &&
-        outParamType.Kind() != reflect.Struct &&
-        !(outParamType.Kind() == reflect.Ptr &&
-            outParamType.Elem().Kind() != reflect.Interface &&
-            outParamType/*FIXME: .Elem()*/.Kind() != reflect.Struct) {
-        return errors.New("Invalid factory function: invalid type (" +
-            outParamType.Kind().String() +
-            ") of the first OUT parameters " +
-            "(must be interface/struct or *interface/*struct)")
-    }
-    if numOut == 2 {
-        if beanFactory.type_.Out(1).Kind() != reflect.Interface ||
-            !beanFactory.type_.Out(1).Implements(reflect.TypeOf((*error)(nil)).Elem()) {
-            return errors.New("Invalid factory function: invalid type of " +
-                "the second OUT parameter (must implement 'error')")
-        }
-    }
     return nil
 }
 
 func validateValue(field reflect.StructField) error {
-    tag := field.Tag.Get("value")
-    if tag == "" {
-        return nil // FIXME: not correct behaviour
+    tag, hasTag := field.Tag.Lookup(TagValue)
+    if !hasTag {
+        return errors.New("Field " + field.Name + " of type " + field.Type.String() +
+            " must have " + TagValue + " tag")
     }
     if !(strings.HasPrefix(tag, ValueTagPrefix) && strings.HasSuffix(tag, ValueTagSuffix)) {
         return errors.New("Invalid value tag format")
     }
+    if validateTag, ok := field.Tag.Lookup(TagValidate); ok {
+        constraints, e := parseConstraints(validateTag)
+        if e != nil {
+            return errors.Wrap(e, "Invalid "+TagValidate+" tag of field "+field.Name)
+        }
+        for _, constraint := range constraints {
+            if e := constraint.validateFor(field.Type); e != nil {
+                return errors.Wrap(e, "Invalid "+TagValidate+" tag of field "+field.Name)
+            }
+        }
+    }
     return nil
 }
-
@@ -0,0 +1,40 @@
+package pp_ioc
+
+import (
+    "reflect"
+)
+
+const beanOptionsMethodName = "BeanOptions"
+
+// Options of the bean created by the configuration method.
+// Zero values mean defaults: the method name as qualifier,
+// ScopeSingleton and DefaultPriority. CustomScope names the scope
+// registered by Context.RegisterScope and takes precedence over Scope.
+// Exclude marks the exported method which is not a factory, e.g. a helper.
+type BeanOptions struct {
+    Qualifiers  []string
+    Scope       BeanScope
+    CustomScope string
+    Priority    int
+    Exclude     bool
+}
+
+// Configuration which provides the options of its factory methods, by method name.
+// BeanOptions is called on the zero value of the configuration type,
+// so it must not depend on the configuration fields.
+type BeanOptionsProvider interface {
+    BeanOptions() map[string]BeanOptions
+}
+
+func beanOptionsOf(configType reflect.Type) map[string]BeanOptions {
+    if !configType.Implements(reflect.TypeOf((*BeanOptionsProvider)(nil)).Elem()) {
+        return nil
+    }
+    var config reflect.Value
+    if configType.Kind() == reflect.Ptr {
+        config = reflect.New(configType.Elem())
+    } else {
+        config = reflect.Zero(configType)
+    }
+    return config.Interface().(BeanOptionsProvider).BeanOptions()
+}
@@ -0,0 +1,131 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+    log "github.com/sirupsen/logrus"
+    "reflect"
+)
+
+// Replacement of the beans matching the target by the ready instance
+type beanOverride struct {
+    qualifier string
+    type_     reflect.Type
+    instance  interface{}
+}
+
+func newBeanOverride(qualifierOrType interface{}, instance interface{}) (*beanOverride, error) {
+    qualifier, type_ := parseQualifierOrType(qualifierOrType)
+    if qualifier == "" && type_ == nil {
+        return nil, errors.New("Invalid override target: qualifier or type expected")
+    }
+    if instance == nil {
+        return nil, errors.New("Invalid override instance: nil")
+    }
+    return &beanOverride{
+        qualifier: qualifier,
+        type_:     type_,
+        instance:  instance,
+    }, nil
+}
+
+// Accepts a qualifier string, a reflect.Type or a value of the needed type, e.g. (*Foo)(nil)
+func parseQualifierOrType(qualifierOrType interface{}) (string, reflect.Type) {
+    switch v := qualifierOrType.(type) {
+    case nil:
+        return "", nil
+    case string:
+        return v, nil
+    case reflect.Type:
+        return "", v
+    }
+    return "", reflect.TypeOf(qualifierOrType)
+}
+
+// Creates the dependency which is used to look up the beans by qualifier or type
+func newLookupDependency(qualifier string, type_ reflect.Type) *dependency {
+    if type_ == nil {
+        type_ = reflect.TypeOf((*interface{})(nil)).Elem()
+    }
+    return newBeanDependency("", qualifier, qualifier != "", type_, 0)
+}
+
+func (o *beanOverride) matches(binder *Binder) bool {
+    if binder.beanFactory == nil {
+        return false
+    }
+    definition := &beanDefinition{key: binder.buildBindKey()}
+    if o.qualifier != "" {
+        return definition.isSuitableForDependencyByQualifier(newLookupDependency(o.qualifier, nil))
+    }
+    return definition.key.type_ == o.type_ ||
+        definition.isSuitableForDependencyByType(newLookupDependency("", o.type_))
+}
+
+// Creates the binder which returns the instance as the given type. The replacement keeps
+// the qualifiers, markers, aliases, scope and priority of the replaced binder,
+// so it is found and injected the same way.
+func (o *beanOverride) createBinder(replaced *Binder, type_ reflect.Type) (*Binder, error) {
+    instanceValue := reflect.ValueOf(o.instance)
+    if !instanceValue.Type().AssignableTo(type_) {
+        return nil, errors.New("Cannot override bean of type " + type_.String() +
+            " with instance of type " + instanceValue.Type().String())
+    }
+    errorType := reflect.TypeOf((*error)(nil)).Elem()
+    factoryType := reflect.FuncOf(nil, []reflect.Type{type_, errorType}, false)
+    factory := reflect.MakeFunc(factoryType, func([]reflect.Value) []reflect.Value {
+        result := reflect.New(type_).Elem()
+        result.Set(instanceValue)
+        return []reflect.Value{result, reflect.Zero(errorType)}
+    })
+    binder := NewBinder().
+        Qualifiers(replaced.qualifiers...).
+        Alias(replaced.aliases...).
+        Priority(replaced.priority).
+        Factory(factory.Interface())
+    binder.markers = replaced.markers
+    binder.scope = replaced.scope
+    binder.scopeName = replaced.scopeName
+    binder.intercepts = replaced.intercepts
+    binder.lazy = replaced.lazy
+    binder.module = replaced.module
+    return binder, nil
+}
+
+// Replaces the binders matching the overrides
+func (ctx *contextImpl) applyOverrides(binders []*Binder) ([]*Binder, error) {
+    for _, override := range ctx.overrides {
+        var res []*Binder
+        var replacements []*Binder
+        for _, binder := range binders {
+            if binder.decorates != nil || !override.matches(binder) {
+                res = append(res, binder)
+                continue
+            }
+            replacement, e := override.createBinder(binder, binder.buildBindKey().type_)
+            if e != nil {
+                return nil, e
+            }
+            replacements = append(replacements, replacement)
+            ctx.logger.WithFields(log.Fields{
+                "binder": binder.String(),
+            }).Info("Binder overridden")
+        }
+        if len(replacements) == 0 {
+            replaced := NewBinder()
+            if override.qualifier != "" {
+                replaced.Qualifiers(override.qualifier)
+            }
+            type_ := override.type_
+            if type_ == nil {
+                type_ = reflect.TypeOf(override.instance)
+            }
+            replacement, e := override.createBinder(replaced, type_)
+            if e != nil {
+                return nil, e
+            }
+            replacements = append(replacements, replacement)
+        }
+        binders = append(res, replacements...)
+    }
+    return binders, nil
+}
@@ -10,7 +10,17 @@ type BeanScope int
 const (
     ScopeUnknown   BeanScope = -1
     ScopeSingleton           = iota
+    // Bean created for every lookup and injection, not instantiated by Build.
+    // Its instances are not disposed by the context.
     ScopePrototype
+    // Singleton which is rebuilt together with its dependents
+    // when any property injected into it changes
+    ScopeRefresh
+    // Scope registered by Context.RegisterScope
+    ScopeCustom
+    // Bean created once per scope started by WithScope,
+    // singletons can use it only through Provider
+    ScopeRequest
 )
 
 func FromString(s string) (BeanScope, error) {
@@ -19,6 +29,10 @@ func FromString(s string) (BeanScope, error) {
         return ScopeSingleton, nil
     case "prototype":
         return ScopePrototype, nil
+    case "refresh":
+        return ScopeRefresh, nil
+    case "request":
+        return ScopeRequest, nil
     }
     return ScopeUnknown, errors.New("Cannot parse bean scope from string " + s)
 }
@@ -29,6 +43,12 @@ func (bs BeanScope) String() string {
         return "Singleton"
     case ScopePrototype:
         return "Prototype"
+    case ScopeRefresh:
+        return "Refresh"
+    case ScopeCustom:
+        return "Custom"
+    case ScopeRequest:
+        return "Request"
     }
     return "Unknown scope"
 }
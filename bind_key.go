@@ -7,9 +7,48 @@ import (
 
 type bindKey struct {
     qualifiers []string
+    markers    []reflect.Type
     type_      reflect.Type
 }
 
 func (b *bindKey) String() string {
-    return "Key{[" + strings.Join(b.qualifiers, ",") + "]:" + b.type_.String() + "}"
+    markers := ""
+    if len(b.markers) > 0 {
+        var names []string
+        for _, marker := range b.markers {
+            names = append(names, marker.String())
+        }
+        markers = "<" + strings.Join(names, ",") + ">"
+    }
+    return "Key{[" + strings.Join(b.qualifiers, ",") + "]" + markers + ":" + b.type_.String() + "}"
+}
+
+func (b *bindKey) hasQualifier(qualifier string) bool {
+    for _, q := range b.qualifiers {
+        if q == qualifier {
+            return true
+        }
+    }
+    return false
+}
+
+func (b *bindKey) hasMarker(marker reflect.Type) bool {
+    for _, m := range b.markers {
+        if m == marker {
+            return true
+        }
+    }
+    return false
+}
+
+func (b *bindKey) hasSameMarkers(other *bindKey) bool {
+    if len(b.markers) != len(other.markers) {
+        return false
+    }
+    for _, marker := range other.markers {
+        if !b.hasMarker(marker) {
+            return false
+        }
+    }
+    return true
 }
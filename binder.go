@@ -2,7 +2,6 @@ package pp_ioc
 
 import (
     "github.com/pkg/errors"
-    "github.com/wlad031/pp-algo/list"
     "reflect"
     "strconv"
     "strings"
@@ -10,9 +9,19 @@ import (
 
 type Binder struct {
     qualifiers  []string
+    aliases     []string
+    markers     []reflect.Type
+    decorates   *dependency
+    intercepts  []*dependency
     scope       BeanScope
+    scopeName   string
     beanFactory *beanFactory
     priority    int
+    overrides   bool
+    lazy        bool
+    module      string
+
+    isConfiguration bool
 }
 
 func NewBinder() *Binder {
@@ -24,7 +33,11 @@ func NewBinder() *Binder {
 }
 
 func (b *Binder) String() string {
-    return "Binder{" + strconv.Itoa(b.priority) + ":" + b.scope.String() +
+    scope := b.scope.String()
+    if b.scope == ScopeCustom {
+        scope = b.scopeName
+    }
+    return "Binder{" + strconv.Itoa(b.priority) + ":" + scope +
         ":[" + strings.Join(b.qualifiers, ",") + "]}"
 }
 
@@ -33,16 +46,85 @@ func (b *Binder) Scope(scope BeanScope) *Binder {
     return b
 }
 
+// Sets the scope registered by Context.RegisterScope
+func (b *Binder) CustomScope(name string) *Binder {
+    b.scope = ScopeCustom
+    b.scopeName = name
+    return b
+}
+
 func (b *Binder) Qualifiers(qualifiers ...string) *Binder {
     b.qualifiers = qualifiers
     return b
 }
 
+// Adds deprecated names of the bean. Injecting or looking the bean up
+// by an alias works, but logs a deprecation warning.
+func (b *Binder) Alias(aliases ...string) *Binder {
+    b.aliases = append(b.aliases, aliases...)
+    return b
+}
+
+// Qualifies the bean by the marker types, e.g. QualifiedBy(ReadReplica{}).
+// Such beans are injected using the Qualified wrapper embedding the markers.
+func (b *Binder) QualifiedBy(markers ...interface{}) *Binder {
+    for _, marker := range markers {
+        b.markers = append(b.markers, markerType(reflect.TypeOf(marker)))
+    }
+    return b
+}
+
+// Makes the binder a decorator of the bean matching the qualifier or type (see Context.Override).
+// The factory receives the decorated instance as its first param, e.g.
+// func(inner Service, deps Deps) (Service, error). Dependents get the outermost
+// decorator, decorators with higher priority wrap the ones with lower.
+func (b *Binder) Decorates(qualifierOrType interface{}) *Binder {
+    qualifier, type_ := parseQualifierOrType(qualifierOrType)
+    b.decorates = newLookupDependency(qualifier, type_)
+    return b
+}
+
+// Applies the MethodInterceptor bean to the beans matching the qualifiers or types
+// (see Context.Override). Intercepted beans must be interfaces with generated proxies.
+// Types match only the beans bound as interfaces, not the implementations bound by their own types.
+// Interceptors with higher priority are invoked first.
+func (b *Binder) Intercepts(qualifiersOrTypes ...interface{}) *Binder {
+    for _, qualifierOrType := range qualifiersOrTypes {
+        qualifier, type_ := parseQualifierOrType(qualifierOrType)
+        b.intercepts = append(b.intercepts, newLookupDependency(qualifier, type_))
+    }
+    return b
+}
+
 func (b *Binder) Priority(priority int) *Binder {
     b.priority = priority
     return b
 }
 
+// Makes the singleton lazy: it is created once, on the first lookup or injection
+// instead of Build. Lazy beans are disposed by Close, but not started as Lifecycle.
+func (b *Binder) Lazy() *Binder {
+    b.lazy = true
+    return b
+}
+
+// Marks the binder as an intentional replacement of the definition
+// with the same qualifiers and type
+func (b *Binder) Overrides() *Binder {
+    b.overrides = true
+    return b
+}
+
+// Marks the bean as a configuration: every exported method of the bean returning
+// (T) or (T, error), where T is an interface, a struct or a pointer, becomes a factory
+// and its params are injected. Methods are qualified by their names, unless
+// the configuration provides BeanOptions. Exported helpers matching the rule,
+// e.g. Client(name string) *http.Client, must be excluded by BeanOptions.
+func (b *Binder) AsConfiguration() *Binder {
+    b.isConfiguration = true
+    return b
+}
+
 func (b *Binder) Factory(factoryFunc interface{}) *Binder {
     b.beanFactory = newBeanFactory(factoryFunc, false)
     return b
@@ -51,16 +133,25 @@ func (b *Binder) Factory(factoryFunc interface{}) *Binder {
 func (b *Binder) buildBindKey() *bindKey {
     return &bindKey{
         qualifiers: b.qualifiers,
+        markers:    b.markers,
         type_:      reflect.TypeOf(b.beanFactory.factoryFunction).Out(0),
     }
 }
 
+// Collects the binders for the factory methods of the configuration struct:
+// all suitable exported methods if the binder is marked with AsConfiguration,
+// otherwise the methods named by the factory tags of the struct fields
+func (b *Binder) collectNestedBinders(res *[]*Binder) error {
+    configType := reflect.TypeOf(b.beanFactory.factoryFunction).Out(0)
+    if b.isConfiguration {
+        return b.collectMethodBinders(configType, res)
+    }
+    return b.collectTaggedBinders(configType, res)
+}
+
 // TODO: refactor this function
-// TODO: rework mechanism of configurations:
-//       they should provide somehow the struct
-//       with all data about bean definition
-func (b *Binder) collectNestedBinders(res list.List) error {
-    out := reflect.TypeOf(b.beanFactory.factoryFunction).Out(0)
+func (b *Binder) collectTaggedBinders(configType reflect.Type, res *[]*Binder) error {
+    out := configType
     if out.Kind() == reflect.Ptr {
         out = out.Elem()
     }
@@ -82,13 +173,15 @@ func (b *Binder) collectNestedBinders(res list.List) error {
             }
 
             nestedBinder := NewBinder().Qualifiers(names...)
+            nestedBinder.module = b.module
 
             if hasScope {
-                v, e := FromString(scope)
-                if e != nil {
-                    return e
+                if v, e := FromString(scope); e == nil {
+                    nestedBinder.Scope(v)
+                } else {
+                    // resolved against the registered scopes during binding
+                    nestedBinder.CustomScope(scope)
                 }
-                nestedBinder.Scope(v)
             }
             if hasPriority {
                 v, e := strconv.Atoi(priority)
@@ -98,14 +191,15 @@ func (b *Binder) collectNestedBinders(res list.List) error {
                 nestedBinder.Priority(v)
             }
 
-            f, ok := out.MethodByName(factoryFuncName)
+            // method set of the factory result type, so pointer receivers work too
+            f, ok := configType.MethodByName(factoryFuncName)
             if !ok {
                 return errors.New("Cannot find factory function " + factoryFuncName)
             }
             nestedBinder.Factory(f.Func.Interface())
             nestedBinder.beanFactory.isMethod = true
 
-            res.Append(nestedBinder)
+            *res = append(*res, nestedBinder)
             if e := nestedBinder.collectNestedBinders(res); e != nil {
                 return e
             }
@@ -113,3 +207,69 @@ func (b *Binder) collectNestedBinders(res list.List) error {
     }
     return nil
 }
+
+func (b *Binder) collectMethodBinders(configType reflect.Type, res *[]*Binder) error {
+    if configType.Kind() != reflect.Struct &&
+        !(configType.Kind() == reflect.Ptr && configType.Elem().Kind() == reflect.Struct) {
+        return errors.New("Invalid configuration " + configType.String() + ": struct or *struct expected")
+    }
+    options := beanOptionsOf(configType)
+    for i := 0; i < configType.NumMethod(); i++ {
+        method := configType.Method(i)
+        if method.Name == beanOptionsMethodName || !isFactoryMethod(method) {
+            continue
+        }
+
+        methodOptions, hasOptions := options[method.Name]
+        if hasOptions && methodOptions.Exclude {
+            continue
+        }
+
+        nestedBinder := NewBinder().Qualifiers(method.Name)
+        nestedBinder.module = b.module
+        if hasOptions {
+            if len(methodOptions.Qualifiers) > 0 {
+                nestedBinder.Qualifiers(methodOptions.Qualifiers...)
+            }
+            if methodOptions.Scope != 0 {
+                nestedBinder.Scope(methodOptions.Scope)
+            }
+            if methodOptions.CustomScope != "" {
+                nestedBinder.CustomScope(methodOptions.CustomScope)
+            }
+            nestedBinder.Priority(methodOptions.Priority)
+        }
+        nestedBinder.Factory(method.Func.Interface())
+        nestedBinder.beanFactory.isMethod = true
+
+        *res = append(*res, nestedBinder)
+        if e := nestedBinder.collectNestedBinders(res); e != nil {
+            return e
+        }
+    }
+    for name := range options {
+        if _, ok := configType.MethodByName(name); !ok {
+            return errors.New("Cannot find factory method " + name + " of " + configType.String())
+        }
+    }
+    return nil
+}
+
+// Methods returning (T) or (T, error), where T is interface, struct or pointer
+func isFactoryMethod(method reflect.Method) bool {
+    errorType := reflect.TypeOf((*error)(nil)).Elem()
+    type_ := method.Type
+    if type_.NumOut() == 0 || type_.NumOut() > 2 {
+        return false
+    }
+    if type_.NumOut() == 2 && type_.Out(1) != errorType {
+        return false
+    }
+    out := type_.Out(0)
+    if out == errorType {
+        return false
+    }
+    return out.Kind() == reflect.Interface ||
+        out.Kind() == reflect.Struct ||
+        out.Kind() == reflect.Ptr
+}
@@ -16,15 +16,9 @@ func newBinderContainer() *binderContainer {
     }
 }
 
-func (container *binderContainer) iterate() <-chan *Binder {
-    c := make(chan *Binder)
-    go func() {
-        for _, v := range container.ls {
-            c <- v
-        }
-        close(c)
-    }()
-    return c
+// Returns the underlying slice, callers must not modify it
+func (container *binderContainer) all() []*Binder {
+    return container.ls
 }
 
 func (container *binderContainer) add(binders ...*Binder) error {
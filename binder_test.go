@@ -0,0 +1,60 @@
+package pp_ioc
+
+import (
+    "context"
+    "testing"
+)
+
+type testClient struct {
+    name string
+}
+
+type clientsConfiguration struct{}
+
+func (c *clientsConfiguration) BeanOptions() map[string]BeanOptions {
+    return map[string]BeanOptions{
+        "Client":  {Exclude: true},
+        "Context": {Exclude: true},
+    }
+}
+
+// Helper, its string param is not a bean
+func (c *clientsConfiguration) Client(name string) *testClient {
+    return &testClient{name: name}
+}
+
+// Helper, an unrelated bean otherwise
+func (c *clientsConfiguration) Context() context.Context {
+    return context.Background()
+}
+
+func (c *clientsConfiguration) UsersClient() *testClient {
+    return c.Client("users")
+}
+
+func TestConfigurationSkipsExcludedMethods(t *testing.T) {
+    ctx := NewContext()
+    ctx.NewBinder().
+        AsConfiguration().
+        Factory(func() *clientsConfiguration {
+            return &clientsConfiguration{}
+        })
+    if e := ctx.Build(); e != nil {
+        t.Fatal(e)
+    }
+    defer func() {
+        _ = ctx.Close()
+    }()
+    client, e := ctx.GetBeanByName("UsersClient")
+    if e != nil {
+        t.Fatal(e)
+    }
+    if name := client.(*testClient).name; name != "users" {
+        t.Errorf("Expected the users client, got %s", name)
+    }
+    for _, name := range []string{"Client", "Context"} {
+        if _, e := ctx.GetBeanByName(name); e == nil {
+            t.Errorf("Expected no bean for the excluded method %s", name)
+        }
+    }
+}
@@ -0,0 +1,12 @@
+// Package sample holds the proxy generated by ioc-proxygen for its tests
+package sample
+
+//go:generate go run github.com/wlad031/pp-ioc/cmd/ioc-proxygen
+
+//ioc:proxy
+type Service interface {
+    Ping()
+    Count() int
+    Join(sep string, parts ...string) string
+    Load(id string) (string, error)
+}
@@ -0,0 +1,83 @@
+// Code generated by ioc-proxygen. DO NOT EDIT.
+
+package sample
+
+import (
+	ioc "github.com/wlad031/pp-ioc"
+)
+
+type serviceProxy struct {
+	target Service
+	chain  *ioc.InterceptorChain
+}
+
+func init() {
+	ioc.RegisterProxy((*Service)(nil), func(target interface{}, chain *ioc.InterceptorChain) interface{} {
+		return &serviceProxy{target: target.(Service), chain: chain}
+	})
+}
+
+func (p *serviceProxy) Ping() {
+	_, err := p.chain.Invoke(p.target, "Ping", []interface{}{}, func(args []interface{}) []interface{} {
+		p.target.Ping()
+		return nil
+	})
+	if err != nil {
+		p.chain.DropError("Ping", err)
+	}
+	return
+}
+
+func (p *serviceProxy) Count() int {
+	results, err := p.chain.Invoke(p.target, "Count", []interface{}{}, func(args []interface{}) []interface{} {
+		r0 := p.target.Count()
+		return []interface{}{r0}
+	})
+	if err != nil {
+		p.chain.DropError("Count", err)
+		results = nil
+	}
+	var r0 int
+	if len(results) > 0 {
+		r0, _ = results[0].(int)
+	}
+	return r0
+}
+
+func (p *serviceProxy) Join(a0 string, a1 ...string) string {
+	results, err := p.chain.Invoke(p.target, "Join", []interface{}{a0, a1}, func(args []interface{}) []interface{} {
+		a0, _ := args[0].(string)
+		a1, _ := args[1].([]string)
+		r0 := p.target.Join(a0, a1...)
+		return []interface{}{r0}
+	})
+	if err != nil {
+		p.chain.DropError("Join", err)
+		results = nil
+	}
+	var r0 string
+	if len(results) > 0 {
+		r0, _ = results[0].(string)
+	}
+	return r0
+}
+
+func (p *serviceProxy) Load(a0 string) (string, error) {
+	results, err := p.chain.Invoke(p.target, "Load", []interface{}{a0}, func(args []interface{}) []interface{} {
+		a0, _ := args[0].(string)
+		r0, r1 := p.target.Load(a0)
+		return []interface{}{r0, r1}
+	})
+	var r0 string
+	if len(results) > 0 {
+		r0, _ = results[0].(string)
+	}
+	var r1 error
+	if len(results) > 1 {
+		r1, _ = results[1].(error)
+	}
+	if err != nil {
+		r1 = err
+	}
+	return r0, r1
+}
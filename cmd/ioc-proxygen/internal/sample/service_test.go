@@ -0,0 +1,172 @@
+package sample
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+
+    ioc "github.com/wlad031/pp-ioc"
+)
+
+type serviceImpl struct {
+    pings int
+}
+
+func (s *serviceImpl) Ping() {
+    s.pings++
+}
+
+func (s *serviceImpl) Count() int {
+    return 5
+}
+
+func (s *serviceImpl) Join(sep string, parts ...string) string {
+    return strings.Join(parts, sep)
+}
+
+func (s *serviceImpl) Load(id string) (string, error) {
+    return "loaded " + id, nil
+}
+
+// Records the invocations and proceeds them
+type recordingInterceptor struct {
+    methods []string
+    args    [][]interface{}
+}
+
+func (i *recordingInterceptor) Invoke(inv ioc.Invocation) ([]interface{}, error) {
+    i.methods = append(i.methods, inv.Method())
+    i.args = append(i.args, inv.Args())
+    return inv.Proceed()
+}
+
+// Rejects every invocation
+type denyingInterceptor struct{}
+
+func (denyingInterceptor) Invoke(inv ioc.Invocation) ([]interface{}, error) {
+    return nil, errors.New("Access denied to " + inv.Method())
+}
+
+type serviceConfiguration struct {
+    impl        *serviceImpl
+    interceptor ioc.MethodInterceptor
+}
+
+func (c *serviceConfiguration) Bind(ctx ioc.Context) error {
+    ctx.NewBinder().
+        Qualifiers("service").
+        Factory(func() Service {
+            return c.impl
+        })
+    ctx.NewBinder().
+        Qualifiers("interceptor").
+        Intercepts(reflect.TypeOf((*Service)(nil)).Elem()).
+        Factory(func() ioc.MethodInterceptor {
+            return c.interceptor
+        })
+    return nil
+}
+
+func newInterceptedService(t *testing.T, impl *serviceImpl, interceptor ioc.MethodInterceptor) Service {
+    t.Helper()
+    ctx := ioc.NewContext()
+    if e := ctx.Register(&serviceConfiguration{impl: impl, interceptor: interceptor}); e != nil {
+        t.Fatal(e)
+    }
+    if e := ctx.Build(); e != nil {
+        t.Fatal(e)
+    }
+    t.Cleanup(func() {
+        _ = ctx.Close()
+    })
+    service, e := ctx.GetBeanByName("service")
+    if e != nil {
+        t.Fatal(e)
+    }
+    if _, ok := service.(*serviceProxy); !ok {
+        t.Fatalf("Expected the generated proxy, got %T", service)
+    }
+    return service.(Service)
+}
+
+func TestProxyCallsMethodWithoutResults(t *testing.T) {
+    impl := &serviceImpl{}
+    interceptor := &recordingInterceptor{}
+    service := newInterceptedService(t, impl, interceptor)
+
+    service.Ping()
+    if impl.pings != 1 {
+        t.Errorf("Expected the target to be called once, got %d", impl.pings)
+    }
+    if !reflect.DeepEqual(interceptor.methods, []string{"Ping"}) {
+        t.Errorf("Expected the intercepted Ping, got %v", interceptor.methods)
+    }
+}
+
+func TestProxyPassesVariadicArguments(t *testing.T) {
+    interceptor := &recordingInterceptor{}
+    service := newInterceptedService(t, &serviceImpl{}, interceptor)
+
+    if res := service.Join(",", "a", "b"); res != "a,b" {
+        t.Errorf("Expected a,b, got %s", res)
+    }
+    expected := [][]interface{}{{",", []string{"a", "b"}}}
+    if !reflect.DeepEqual(interceptor.args, expected) {
+        t.Errorf("Expected the variadic args as a slice, got %v", interceptor.args)
+    }
+}
+
+func TestImplementationBoundByItsTypeIsNotIntercepted(t *testing.T) {
+    ctx := ioc.NewContext()
+    e := ctx.Register(&serviceConfiguration{impl: &serviceImpl{}, interceptor: &recordingInterceptor{}})
+    if e != nil {
+        t.Fatal(e)
+    }
+    ctx.NewBinder().
+        Qualifiers("implementation").
+        Factory(func() *serviceImpl {
+            return &serviceImpl{}
+        })
+    if e := ctx.Build(); e != nil {
+        t.Fatal(e)
+    }
+    defer func() {
+        _ = ctx.Close()
+    }()
+    implementation, e := ctx.GetBeanByName("implementation")
+    if e != nil {
+        t.Fatal(e)
+    }
+    if _, ok := implementation.(*serviceImpl); !ok {
+        t.Errorf("Expected the implementation itself, got %T", implementation)
+    }
+}
+
+func TestProxyReturnsErrorOfInterceptor(t *testing.T) {
+    service := newInterceptedService(t, &serviceImpl{}, denyingInterceptor{})
+
+    res, e := service.Load("1")
+    if e == nil || e.Error() != "Access denied to Load" {
+        t.Errorf("Expected the error of the interceptor, got %v", e)
+    }
+    if res != "" {
+        t.Errorf("Expected the zero value, got %s", res)
+    }
+}
+
+func TestProxyDropsErrorOfMethodWithoutErrorResult(t *testing.T) {
+    impl := &serviceImpl{}
+    service := newInterceptedService(t, impl, denyingInterceptor{})
+
+    service.Ping()
+    if impl.pings != 0 {
+        t.Errorf("Expected the target not to be called, got %d calls", impl.pings)
+    }
+    if count := service.Count(); count != 0 {
+        t.Errorf("Expected the zero value, got %d", count)
+    }
+    if res := service.Join(",", "a"); res != "" {
+        t.Errorf("Expected the zero value, got %s", res)
+    }
+}
@@ -0,0 +1,249 @@
+// Command ioc-proxygen generates proxies for the interfaces annotated with
+// the //ioc:proxy comment, so their beans can be intercepted by pp_ioc.MethodInterceptor.
+//
+// Usage:
+//
+//    //ioc:proxy
+//    type Service interface { ... }
+//
+//    //go:generate ioc-proxygen
+package main
+
+import (
+    "bytes"
+    "flag"
+    "fmt"
+    "go/ast"
+    "go/format"
+    "go/parser"
+    "go/printer"
+    "go/token"
+    "io/ioutil"
+    "os"
+    "path"
+    "strconv"
+    "strings"
+)
+
+const annotation = "ioc:proxy"
+
+func main() {
+    source := flag.String("source", os.Getenv("GOFILE"), "file with the annotated interfaces")
+    output := flag.String("output", "", "generated file, <source>_proxy.go by default")
+    flag.Parse()
+    if *source == "" {
+        fail(fmt.Errorf("source is not specified"))
+    }
+    if *output == "" {
+        *output = strings.TrimSuffix(*source, ".go") + "_proxy.go"
+    }
+    code, e := generate(*source)
+    if e != nil {
+        fail(e)
+    }
+    if code == nil {
+        fail(fmt.Errorf("no interfaces annotated with //%s in %s", annotation, *source))
+    }
+    if e := ioutil.WriteFile(*output, code, 0644); e != nil {
+        fail(e)
+    }
+}
+
+func fail(e error) {
+    fmt.Fprintln(os.Stderr, "ioc-proxygen:", e)
+    os.Exit(1)
+}
+
+type generator struct {
+    fset    *token.FileSet
+    file    *ast.File
+    body    bytes.Buffer
+    imports map[string]bool // names of the packages used in the signatures
+}
+
+// Returns nil if the file has no annotated interfaces
+func generate(source string) ([]byte, error) {
+    fset := token.NewFileSet()
+    file, e := parser.ParseFile(fset, source, nil, parser.ParseComments)
+    if e != nil {
+        return nil, e
+    }
+    gen := &generator{fset: fset, file: file, imports: map[string]bool{}}
+    found := false
+    for _, decl := range file.Decls {
+        genDecl, ok := decl.(*ast.GenDecl)
+        if !ok || genDecl.Tok != token.TYPE {
+            continue
+        }
+        for _, spec := range genDecl.Specs {
+            typeSpec := spec.(*ast.TypeSpec)
+            iface, ok := typeSpec.Type.(*ast.InterfaceType)
+            if !ok || !isAnnotated(typeSpec.Doc) && !(len(genDecl.Specs) == 1 && isAnnotated(genDecl.Doc)) {
+                continue
+            }
+            if e := gen.proxy(typeSpec.Name.Name, iface); e != nil {
+                return nil, e
+            }
+            found = true
+        }
+    }
+    if !found {
+        return nil, nil
+    }
+    return gen.source()
+}
+
+func isAnnotated(doc *ast.CommentGroup) bool {
+    if doc == nil {
+        return false
+    }
+    for _, comment := range doc.List {
+        if strings.TrimSpace(strings.TrimPrefix(comment.Text, "//")) == annotation {
+            return true
+        }
+    }
+    return false
+}
+
+func (gen *generator) proxy(name string, iface *ast.InterfaceType) error {
+    proxyName := strings.ToLower(name[:1]) + name[1:] + "Proxy"
+    fmt.Fprintf(&gen.body, `
+type %[1]s struct {
+    target %[2]s
+    chain  *ioc.InterceptorChain
+}
+
+func init() {
+    ioc.RegisterProxy((*%[2]s)(nil), func(target interface{}, chain *ioc.InterceptorChain) interface{} {
+        return &%[1]s{target: target.(%[2]s), chain: chain}
+    })
+}
+`, proxyName, name)
+    for _, method := range iface.Methods.List {
+        if len(method.Names) == 0 {
+            return fmt.Errorf("%s: embedded interfaces are not supported", name)
+        }
+        if e := gen.method(proxyName, method.Names[0].Name, method.Type.(*ast.FuncType)); e != nil {
+            return e
+        }
+    }
+    return nil
+}
+
+func (gen *generator) method(proxyName string, name string, funcType *ast.FuncType) error {
+    var params, args, casts, callArgs []string
+    for _, field := range fieldList(funcType.Params) {
+        i := len(args)
+        arg := "a" + strconv.Itoa(i)
+        type_ := gen.typeString(field)
+        callArg := arg
+        if ellipsis, ok := field.(*ast.Ellipsis); ok {
+            params = append(params, arg+" ..."+gen.typeString(ellipsis.Elt))
+            type_ = "[]" + gen.typeString(ellipsis.Elt)
+            callArg += "..."
+        } else {
+            params = append(params, arg+" "+type_)
+        }
+        args = append(args, arg)
+        casts = append(casts, fmt.Sprintf("%s, _ := args[%d].(%s)", arg, i, type_))
+        callArgs = append(callArgs, callArg)
+    }
+    var resultTypes, results []string
+    for i, field := range fieldList(funcType.Results) {
+        resultTypes = append(resultTypes, gen.typeString(field))
+        results = append(results, "r"+strconv.Itoa(i))
+    }
+
+    fmt.Fprintf(&gen.body, "\nfunc (p *%s) %s(%s) (%s) {\n",
+        proxyName, name, strings.Join(params, ", "), strings.Join(resultTypes, ", "))
+    resultsVar := "results"
+    if len(results) == 0 {
+        resultsVar = "_"
+    }
+    fmt.Fprintf(&gen.body, "%s, err := p.chain.Invoke(p.target, %q, []interface{}{%s}, func(args []interface{}) []interface{} {\n",
+        resultsVar, name, strings.Join(args, ", "))
+    for _, cast := range casts {
+        fmt.Fprintln(&gen.body, cast)
+    }
+    call := fmt.Sprintf("p.target.%s(%s)", name, strings.Join(callArgs, ", "))
+    if len(results) == 0 {
+        fmt.Fprintf(&gen.body, "%s\nreturn nil\n})\n", call)
+    } else {
+        fmt.Fprintf(&gen.body, "%s := %s\nreturn []interface{}{%s}\n})\n",
+            strings.Join(results, ", "), call, strings.Join(results, ", "))
+    }
+    last := len(results) - 1
+    returnsError := last >= 0 && resultTypes[last] == "error"
+    if !returnsError {
+        // the method cannot return the error of the interceptor, so it returns zero values
+        fmt.Fprintf(&gen.body, "if err != nil {\np.chain.DropError(%q, err)\n", name)
+        if len(results) > 0 {
+            fmt.Fprintf(&gen.body, "results = nil\n")
+        }
+        fmt.Fprintf(&gen.body, "}\n")
+    }
+    for i, result := range results {
+        fmt.Fprintf(&gen.body, "var %s %s\nif len(results) > %d {\n%s, _ = results[%d].(%s)\n}\n",
+            result, resultTypes[i], i, result, i, resultTypes[i])
+    }
+    if returnsError {
+        fmt.Fprintf(&gen.body, "if err != nil {\n%s = err\n}\n", results[last])
+    }
+    fmt.Fprintf(&gen.body, "return %s\n}\n", strings.Join(results, ", "))
+    return nil
+}
+
+// Flattens the fields, so each one has the single name
+func fieldList(list *ast.FieldList) []ast.Expr {
+    var res []ast.Expr
+    if list == nil {
+        return res
+    }
+    for _, field := range list.List {
+        n := len(field.Names)
+        if n == 0 {
+            n = 1
+        }
+        for i := 0; i < n; i++ {
+            res = append(res, field.Type)
+        }
+    }
+    return res
+}
+
+func (gen *generator) typeString(expr ast.Expr) string {
+    ast.Inspect(expr, func(node ast.Node) bool {
+        if selector, ok := node.(*ast.SelectorExpr); ok {
+            if ident, ok := selector.X.(*ast.Ident); ok {
+                gen.imports[ident.Name] = true
+            }
+        }
+        return true
+    })
+    var buf bytes.Buffer
+    _ = printer.Fprint(&buf, gen.fset, expr)
+    return buf.String()
+}
+
+func (gen *generator) source() ([]byte, error) {
+    var buf bytes.Buffer
+    fmt.Fprintf(&buf, "// Code generated by ioc-proxygen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", gen.file.Name.Name)
+    fmt.Fprintln(&buf, `ioc "github.com/wlad031/pp-ioc"`)
+    for _, spec := range gen.file.Imports {
+        importPath, _ := strconv.Unquote(spec.Path.Value)
+        name, alias := path.Base(importPath), ""
+        if spec.Name != nil {
+            name, alias = spec.Name.Name, spec.Name.Name
+        }
+        if gen.imports[name] {
+            fmt.Fprintln(&buf, alias, spec.Path.Value)
+        }
+    }
+    fmt.Fprintln(&buf, ")")
+    buf.Write(gen.body.Bytes())
+    code, e := format.Source(buf.Bytes())
+    if e != nil {
+        return nil, fmt.Errorf("cannot format the generated code: %v", e)
+    }
+    return code, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+    "bytes"
+    "io/ioutil"
+    "testing"
+)
+
+// The proxy of the sample is tested against the context, so it must be up to date
+func TestGenerateSample(t *testing.T) {
+    code, e := generate("internal/sample/service.go")
+    if e != nil {
+        t.Fatal(e)
+    }
+    expected, e := ioutil.ReadFile("internal/sample/service_proxy.go")
+    if e != nil {
+        t.Fatal(e)
+    }
+    if !bytes.Equal(code, expected) {
+        t.Errorf("Generated proxy differs from internal/sample/service_proxy.go, run go generate:\n%s", code)
+    }
+}
+
+func TestGenerateReturnsNilWithoutAnnotations(t *testing.T) {
+    code, e := generate("main.go")
+    if e != nil {
+        t.Fatal(e)
+    }
+    if code != nil {
+        t.Errorf("Expected no code, got:\n%s", code)
+    }
+}
@@ -1,5 +1,36 @@
 package pp_ioc
 
+import (
+    "reflect"
+)
+
 type Configuration interface {
     Bind(ctx Context) error
 }
+
+// Configuration which requires other configurations. Imported configurations
+// are registered before the importing one, each of them only once.
+type ImportingConfiguration interface {
+    Imports() []Configuration
+}
+
+// Configuration with the module name, which is shown in logs and graph exports.
+// The name is also used to de-duplicate imports: a configuration of the same type with
+// the same name is registered once, the one which comes first wins, while a configuration
+// of a different type with the same name is rejected. Configurations without it are
+// named after their type, so give a distinct name to each instance of a type which
+// has to be registered several times.
+type NamedConfiguration interface {
+    Name() string
+}
+
+func configurationName(configuration Configuration) string {
+    if named, ok := configuration.(NamedConfiguration); ok {
+        return named.Name()
+    }
+    type_ := reflect.TypeOf(configuration)
+    for type_.Kind() == reflect.Ptr {
+        type_ = type_.Elem()
+    }
+    return type_.String()
+}
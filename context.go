@@ -2,9 +2,10 @@ package pp_ioc
 
 import (
     "github.com/pkg/errors"
-    "github.com/wlad031/pp-algo/list"
     logCtx "github.com/wlad031/pp-logging"
     "reflect"
+    "sync"
+    "time"
 )
 
 //region public
@@ -14,23 +15,52 @@ const (
     ContextBeanName     = "ApplicationContext"
     EnvironmentBeanName = "Environment"
 
-    TagValue      = "value"
-    TagFactory    = "factory"
-    TagQualifiers = "qualifiers"
-    TagQualifier  = "qualifier"
-    TagPriority   = "priority"
-    TagScope      = "scope"
+    TagValue       = "value"
+    TagFactory     = "factory"
+    TagQualifiers  = "qualifiers"
+    TagQualifier   = "qualifier"
+    TagPriority    = "priority"
+    TagScope       = "scope"
+    TagValidate    = "validate"
+    TagDescription = "desc"
+    TagInject      = "inject"
 )
 
+// Binding and registration must happen before Build. After that, lookups are safe
+// for concurrent use, but they are rejected while the context is building or refreshing.
 type Context interface {
     // Creates and returns new Binder instance
     NewBinder() *Binder
+    // Creates the binder of the property source with PropertySourceHighestPriority.
+    // Sources with lower priorities override the properties of the ones with higher.
     NewPropertySourceBinder() *Binder
+    NewPropertyDecryptorBinder() *Binder
 
-    GetBeanByName(name string) (interface{}, error)              // TODO: implement
-    GetBeanByType(type_ reflect.Type) (interface{}, error)       // TODO: implement
+    // Registers the configurations together with the configurations they import.
+    // Binders created during registration belong to the module of the configuration.
+    Register(configurations ...Configuration) error
+
+    GetBeanByName(name string) (interface{}, error) // TODO: implement
+    // Returns the bean of exactly this type or the only bean suitable for this type.
+    // Beans bound as *T suit the interfaces implemented by *T, including its pointer receiver methods.
+    GetBeanByType(type_ reflect.Type) (interface{}, error)
     GetAllBeansByType(type_ reflect.Type) ([]interface{}, error) // TODO: implement
 
+    // Fills the exported fields of the struct pointer which have qualifier, value
+    // or inject tags. A non-empty inject tag is treated as a qualifier.
+    Autowire(target interface{}) error
+    // The same as Autowire, but unexported fields are filled too
+    AutowireUnexported(target interface{}) error
+
+    // Calls the function resolving its params the same way as for bean factories,
+    // including tagged param structs. Returns all the results of the function,
+    // if the last one is a non-nil error, it is returned as the error too.
+    Invoke(fn interface{}) ([]interface{}, error)
+
+    // Registers the custom scope, which can be used by Binder.CustomScope
+    // and scope tags. Must be called before Build.
+    RegisterScope(name string, scope Scope) error
+
     // Returns the context's environment
     GetEnvironment() Environment
 
@@ -38,8 +68,51 @@ type Context interface {
     // to instantiate all the beans.
     Build() error
 
+    // Builds only the beans matching the qualifier or type (see Override)
+    // and everything they depend on
+    BuildFor(qualifierOrType interface{}) error
+
+    // Replaces the beans matching the qualifier or type by the ready instance.
+    // The target is a qualifier string, a reflect.Type or a value of the needed type,
+    // e.g. (*Foo)(nil). Must be called before Build.
+    Override(qualifierOrType interface{}, instance interface{}) error
+
+    // Starts the Lifecycle beans in order of their phases. Must be called after Build.
+    // If any bean fails to start, the already started ones are stopped.
+    Start() error
+
+    // Stops the started Lifecycle beans in reverse order of their phases.
+    // The timeout is shared by all the beans.
+    Stop(timeout time.Duration) error
+
+    // Stops the Lifecycle beans, disposes all the Disposer beans in the reverse order
+    // of their instantiation and stops watching the property sources
+    Close() error
+
+    // Returns the metadata of all the properties injected into the beans.
+    // Beans are not instantiated.
+    PropertyMetadata() ([]PropertyMetadata, error)
+
+    // Returns the dependency graph in the given format.
+    // Beans are not instantiated.
+    ExportGraph(format GraphFormat) (string, error)
+
+    // Checks the wiring without calling any factory: missing and ambiguous beans,
+    // cycles, invalid value tags and default values. Returns every problem found.
+    Validate() error
+
+    // If set (the default), Build stops at the first error. Otherwise, Build collects
+    // all the independent errors, skipping the beans which depend on the failed ones.
+    FailFast(failFast bool)
+
+    // Sets the policy for the definitions with the same qualifiers and type.
+    // OverridePolicyError is the default.
+    OverridePolicy(policy OverridePolicy)
+
     // Refreshes the context. All bean definitions will stay the same,
-    // but all beans will be reinstantiated.
+    // but all beans will be reinstantiated. Lifecycle beans are stopped
+    // and must be started again. If the environment was watching the property
+    // sources, the new one watches them with the same poll interval.
     Refresh() error
 }
 
@@ -55,7 +128,11 @@ func NewContext() Context {
         postProcessors:       newPostProcessorContainer(),
         environment:          newEnvironment(),
         initialized:          false,
+        failFast:             true,
+        modules:              map[string]Configuration{},
+        scopes:               map[string]Scope{},
     }
+    ctx.environment.Subscribe(ctx.onPropertiesChanged)
     return &ctx
 }
 
@@ -72,11 +149,32 @@ type contextImpl struct {
     container            *beanContainer
     postProcessors       *postProcessorContainer
     environment          Environment
-    initialized          bool // TODO: use this field somewhere
+    bound                bool
+    bindError            error
+    graphBuilt           bool
+    graphError           error
+    failFast             bool
+    overrides            []*beanOverride
+    overridePolicy       OverridePolicy
+    modules              map[string]Configuration
+    currentModule        string
+    started              []*bean
+    scopes               map[string]Scope
+    initialized          bool
+
+    // Guards initialized, container and environment. Lookups are rejected
+    // until the context is built and only take a snapshot under the lock,
+    // so factories never run with the lock held and cannot deadlock on it.
+    mutex sync.RWMutex
+    // Serializes building, refreshing and closing
+    buildMutex sync.Mutex
+    // Guards the started Lifecycle beans
+    lifecycleMutex sync.Mutex
 }
 
 func (ctx *contextImpl) NewBinder() *Binder {
     binder := NewBinder()
+    binder.module = ctx.currentModule
     _ = ctx.binders.add(binder)
     return binder
 }
@@ -87,29 +185,61 @@ func (ctx *contextImpl) NewPropertySourceBinder() *Binder {
         Scope(ScopeSingleton)
 }
 
+func (ctx *contextImpl) NewPropertyDecryptorBinder() *Binder {
+    return ctx.NewBinder().
+        Priority(PropertyDecryptorPriority).
+        Scope(ScopeSingleton)
+}
+
 func (ctx *contextImpl) GetBeanByName(name string) (interface{}, error) {
-    for bean := range ctx.container.iterate() {
-        for _, beanName := range bean.definition.key.qualifiers {
-            if beanName == name {
-                return bean.instance, nil
-            }
+    res, e := ctx.snapshotBuilt("get bean "+name, nil)
+    if e != nil {
+        return nil, e
+    }
+    // the index contains both the qualifiers and the aliases, qualifiers win
+    var aliased *beanDefinition
+    for _, definition := range res.index.byQualifier[name] {
+        if !res.hasBean(definition) {
+            continue
         }
+        if definition.key.hasQualifier(name) {
+            return ctx.getDefinitionInstance(res, definition)
+        }
+        if aliased == nil {
+            aliased = definition
+        }
+    }
+    if aliased != nil {
+        warnDeprecatedAlias(ctx.logger, name, aliased)
+        return ctx.getDefinitionInstance(res, aliased)
     }
     return nil, errors.New("Cannot find bean with name " + name)
 }
 
 func (ctx *contextImpl) GetBeanByType(type_ reflect.Type) (interface{}, error) {
-    for bean := range ctx.container.iterate() {
-        if bean.definition.key.type_ == type_ {
-            return bean.instance, nil
+    res, e := ctx.snapshotBuilt("get bean of type "+type_.String(), nil)
+    if e != nil {
+        return nil, e
+    }
+    for _, definition := range res.index.byKeyType[type_] {
+        if res.hasBean(definition) {
+            return ctx.getDefinitionInstance(res, definition)
         }
     }
-    return nil, nil
+    value, e := ctx.findDependencyBeanValue(res, nil, newLookupDependency("", type_))
+    if e != nil {
+        return nil, e
+    }
+    return value.Interface(), nil
 }
 
 func (ctx *contextImpl) GetAllBeansByType(type_ reflect.Type) ([]interface{}, error) {
+    snapshot, e := ctx.snapshotBuilt("get beans of type "+type_.String(), nil)
+    if e != nil {
+        return nil, e
+    }
     var res []interface{}
-    for bean := range ctx.container.iterate() {
+    for _, bean := range snapshot.beans {
         if bean.definition.key.type_.Implements(type_.Elem()) { // FIXME: doesn't work correctly
             res = append(res, bean.instance)
         }
@@ -117,68 +247,250 @@ func (ctx *contextImpl) GetAllBeansByType(type_ reflect.Type) ([]interface{}, er
     return res, nil
 }
 
+func (ctx *contextImpl) FailFast(failFast bool) {
+    ctx.failFast = failFast
+}
+
+func (ctx *contextImpl) OverridePolicy(policy OverridePolicy) {
+    ctx.overridePolicy = policy
+}
+
 func (ctx *contextImpl) GetEnvironment() Environment {
+    ctx.mutex.RLock()
+    defer ctx.mutex.RUnlock()
     return ctx.environment
 }
 
 func (ctx *contextImpl) Build() error {
+    ctx.buildMutex.Lock()
+    defer ctx.buildMutex.Unlock()
+    return ctx.build(func(definition *beanDefinition) bool {
+        return true
+    })
+}
+
+func (ctx *contextImpl) BuildFor(qualifierOrType interface{}) error {
+    ctx.buildMutex.Lock()
+    defer ctx.buildMutex.Unlock()
+    qualifier, type_ := parseQualifierOrType(qualifierOrType)
+    if e := ctx.buildGraph(); e != nil {
+        return e
+    }
+    lookup := newLookupDependency(qualifier, type_)
+    var targets []int
+    for _, definition := range ctx.beanDefinitions.all() {
+        if isDefinitionSuitable(definition, lookup) {
+            targets = append(targets, definition.graphIndex)
+        }
+    }
+    if len(targets) == 0 {
+        return newMissingBeanError(nil, lookup, ctx.beanDefinitions.ls)
+    }
+    included := ctx.graph.collectDependencies(targets)
+    return ctx.build(func(definition *beanDefinition) bool {
+        return included[definition.graphIndex]
+    })
+}
+
+func (ctx *contextImpl) Override(qualifierOrType interface{}, instance interface{}) error {
+    if ctx.bound {
+        return errors.New("Beans cannot be overridden after the context is bound")
+    }
+    override, e := newBeanOverride(qualifierOrType, instance)
+    if e != nil {
+        return e
+    }
+    ctx.overrides = append(ctx.overrides, override)
+    return nil
+}
+
+func (ctx *contextImpl) Close() error {
+    ctx.logger.Info("Closing the context...")
+    // the watcher may wait for the build lock to rebuild refresh scoped beans
+    ctx.GetEnvironment().StopWatching()
+    ctx.buildMutex.Lock()
+    defer ctx.buildMutex.Unlock()
+    return ctx.close()
+}
+
+// Stops and disposes all the beans and forgets the instances of the definitions,
+// including the ones in custom scopes. Must be called with the build lock held.
+func (ctx *contextImpl) close() error {
+    errs := &MultiError{}
+    errs.append(ctx.Stop(DefaultStopTimeout))
+    ctx.setInitialized(false)
+    beans := append([]*bean{}, ctx.container.all()...)
+    for _, definition := range ctx.beanDefinitions.all() {
+        // lazy singletons are not in the container, they are created after its beans
+        if b := definition.reset(); b != nil && definition.lazy {
+            beans = append(beans, b)
+        }
+    }
+    for i := len(beans) - 1; i >= 0; i-- {
+        if disposer, ok := beans[i].instance.(Disposer); ok {
+            if e := disposer.Dispose(); e != nil {
+                errs.append(errors.Wrap(e, "Cannot dispose "+beans[i].definition.shortString()))
+            }
+        }
+    }
+    ctx.mutex.Lock()
+    ctx.container = newBeanContainer()
+    ctx.mutex.Unlock()
+    return errs.errorOrNil()
+}
+
+// Builds the context instantiating only the definitions accepted by the filter.
+// Must be called with the build lock held.
+func (ctx *contextImpl) build(include func(definition *beanDefinition) bool) error {
     ctx.logger.Info("Building the context...")
+    ctx.setInitialized(false)
+    // lookups may still use the snapshot of the container, so it is not changed in place
+    ctx.mutex.Lock()
+    ctx.container = ctx.container.clone()
+    ctx.mutex.Unlock()
     e := ctx.bindEverything()
     if e != nil {
         return e
     }
-    e = ctx.graph.build(ctx.beanDefinitions)
+    e = ctx.buildGraph()
     if e != nil {
         return e
     }
-    e = ctx.instantiateBeans()
+    e = ctx.instantiateBeans(include)
     if e != nil {
         return e
     }
+    // post processors can already look the beans up
+    ctx.setInitialized(true)
     e = ctx.runPostProcessors()
     if e != nil {
+        ctx.setInitialized(false)
         return e
     }
-    ctx.initialized = true
     return nil
 }
 
+func (ctx *contextImpl) setInitialized(initialized bool) {
+    ctx.mutex.Lock()
+    ctx.initialized = initialized
+    ctx.mutex.Unlock()
+}
+
+func (ctx *contextImpl) PropertyMetadata() ([]PropertyMetadata, error) {
+    ctx.buildMutex.Lock()
+    defer ctx.buildMutex.Unlock()
+    if e := ctx.bindEverything(); e != nil {
+        return nil, e
+    }
+    var res []PropertyMetadata
+    for _, definition := range ctx.beanDefinitions.all() {
+        for _, dependency := range definition.allDependencies() {
+            if dependency.isValue {
+                res = append(res, newPropertyMetadata(definition, dependency))
+            }
+        }
+    }
+    sortPropertyMetadata(res)
+    return res, nil
+}
+
 func (ctx *contextImpl) Refresh() error {
-    panic("implement me") // FIXME: refresh server correctly
-    //ctx.logger.Info("Refreshing the context...")
-    //ctx.beanDefinitions = newBeanDefinitionList()
-    //ctx.graph = newContextGraph()
-    //ctx.container = newBeanContainer()
-    //ctx.postProcessors = newPostProcessorContainer()
-    //ctx.environment = newEnvironment()
-    //ctx.initialized = false
-    //return ctx.Build()
+    ctx.logger.Info("Refreshing the context...")
+    environment := ctx.GetEnvironment()
+    pollInterval, watching := environment.watchingInterval()
+    environment.StopWatching()
+    if e := ctx.refresh(); e != nil {
+        return e
+    }
+    if !watching {
+        return nil
+    }
+    // without the build lock, since watchable sources may publish synchronously
+    return ctx.GetEnvironment().StartWatching(pollInterval)
+}
+
+func (ctx *contextImpl) refresh() error {
+    ctx.buildMutex.Lock()
+    defer ctx.buildMutex.Unlock()
+    if e := ctx.close(); e != nil {
+        return errors.Wrap(e, "Cannot refresh the context")
+    }
+    environment := newEnvironment()
+    environment.Subscribe(ctx.onPropertiesChanged)
+    ctx.mutex.Lock()
+    ctx.environment = environment
+    ctx.mutex.Unlock()
+    ctx.postProcessors = newPostProcessorContainer()
+    ctx.graph.failed = map[int]bool{}
+    return ctx.build(func(definition *beanDefinition) bool {
+        return true
+    })
 }
 
+// Binds everything only once, so Build can be called
+// after the metadata has been collected
 func (ctx *contextImpl) bindEverything() error {
+    if !ctx.bound {
+        ctx.bound = true
+        ctx.bindError = ctx.bindAllBinders()
+    }
+    return ctx.bindError
+}
+
+// Builds the dependency graph only once
+func (ctx *contextImpl) buildGraph() error {
+    if e := ctx.bindEverything(); e != nil {
+        return e
+    }
+    if !ctx.graphBuilt {
+        ctx.graphBuilt = true
+        ctx.graphError = ctx.graph.build(ctx.beanDefinitions)
+    }
+    return ctx.graphError
+}
+
+func (ctx *contextImpl) bindAllBinders() error {
     _ = ctx.binders.add(
         ctx.createContextBinder(),
         ctx.createEnvironmentBinder(),
     )
 
-    nestedBinders := list.New()
-    for binder := range ctx.binders.iterate() {
-        e := binder.collectNestedBinders(nestedBinders)
+    var nestedBinders []*Binder
+    for _, binder := range ctx.binders.all() {
+        e := binder.collectNestedBinders(&nestedBinders)
         if e != nil {
             return e
         }
     }
-    for b := range nestedBinders.Iterate() {
-        _ = ctx.binders.add(b.(*Binder))
-    }
+    _ = ctx.binders.add(nestedBinders...)
 
-    for binder := range ctx.binders.iterate() {
+    binders, e := ctx.applyOverrides(ctx.binders.ls)
+    if e != nil {
+        return e
+    }
+    ctx.binders.ls = binders
+
+    errs := &MultiError{}
+    var decorators []*Binder
+    for _, binder := range ctx.binders.all() {
+        if binder.decorates != nil {
+            decorators = append(decorators, binder)
+            continue
+        }
         e := ctx.bind(binder)
         if e != nil {
-            return errors.Wrap(e, "Error happened during binding "+binder.String())
+            errs.append(errors.Wrap(e, "Error happened during binding "+binder.String()))
         }
     }
-    return nil
+    // decorated definitions must already be bound
+    for _, binder := range decorators {
+        e := ctx.bindDecorator(binder)
+        if e != nil {
+            errs.append(errors.Wrap(e, "Error happened during binding decorator "+binder.String()))
+        }
+    }
+    errs.append(ctx.bindInterceptors())
+    return errs.errorOrNil()
 }
 
 // Creates a binder for context itself.
@@ -210,17 +522,36 @@ func (ctx *contextImpl) bind(binder *Binder) error {
         return e
     }
 
+    customScope, e := ctx.resolveCustomScope(binder)
+    if e != nil {
+        return e
+    }
+    if binder.lazy && binder.scope != ScopeSingleton && binder.scope != ScopeRefresh {
+        return errors.New("Only singleton and refresh scoped beans can be lazy")
+    }
+
     dependencies, paramTypes := binder.beanFactory.collectDependencies()
+    for _, dependency := range dependencies {
+        if dependency.isProvider && !dependency.hasQualifier {
+            return errors.New("Provider " + graphEdgeLabel(dependency) + " must have a qualifier")
+        }
+    }
     definition := &beanDefinition{
         key:          binder.buildBindKey(),
+        aliases:      binder.aliases,
+        intercepts:   binder.intercepts,
         dependencies: dependencies,
         paramTypes:   paramTypes,
         scope:        binder.scope,
+        scopeName:    binder.scopeName,
+        customScope:  customScope,
         priority:     binder.priority,
         factory:      binder.beanFactory,
+        overrides:    binder.overrides,
+        lazy:         binder.lazy,
+        module:       binder.module,
     }
-    ctx.beanDefinitions.add(definition)
-    return nil
+    return ctx.addDefinition(definition)
 }
 
 func (ctx *contextImpl) addBeanToContainers(bean *bean) error {
@@ -230,6 +561,11 @@ func (ctx *contextImpl) addBeanToContainers(bean *bean) error {
             return e
         }
     }
+    if bean.definition.isPropertyDecryptor() {
+        if e := ctx.environment.addPropertyDecryptor(bean); e != nil {
+            return e
+        }
+    }
     if bean.definition.isPostProcessor() {
         if e := ctx.postProcessors.add(bean); e != nil {
             return e
@@ -238,112 +574,255 @@ func (ctx *contextImpl) addBeanToContainers(bean *bean) error {
     return nil
 }
 
-func (ctx *contextImpl) getDependencyValueInstance(dependency *dependency) (reflect.Value, error) {
-    var propertyValue string
-    var e error
+func resolvePropertyValue(environment Environment, dependency *dependency) (string, error) {
     if dependency.valueProvider.hasDefault {
-        propertyValue = ctx.environment.GetPropertyOrDefault(dependency.qualifier, dependency.valueProvider.defaultValue)
-    } else {
-        propertyValue, e = ctx.environment.GetProperty(dependency.qualifier)
-        if e != nil {
-            return reflect.Value{}, e
+        return environment.GetPropertyOrDefault(dependency.qualifier, dependency.valueProvider.defaultValue), nil
+    }
+    return environment.GetProperty(dependency.qualifier)
+}
+
+func (ctx *contextImpl) getDependencyValueInstance(
+    res *resolution,
+    requester *beanDefinition,
+    dependency *dependency,
+) (reflect.Value, error) {
+    propertyValue, e := resolvePropertyValue(res.environment, dependency)
+    if e == nil {
+        var value reflect.Value
+        if value, e = dependency.parsePropertyValue(propertyValue); e == nil {
+            return value, nil
         }
     }
-    return dependency.parsePropertyValue(propertyValue)
+    return reflect.Value{}, &PropertyError{
+        Definition: definitionName(requester),
+        Field:      dependency.name,
+        Property:   dependency.qualifier,
+        Err:        e,
+    }
 }
 
-func (ctx *contextImpl) findDependencyBeanValue(dependency *dependency) (reflect.Value, error) {
-    var isBeanFound = false
-    var foundBean reflect.Value
+// Checks all the values injected into all the beans and returns every violation found
+func (ctx *contextImpl) validateValues(include func(definition *beanDefinition) bool) error {
+    errs := &MultiError{}
+    for _, definition := range ctx.beanDefinitions.all() {
+        if !include(definition) {
+            continue
+        }
+        errs.append(ctx.validateDefinitionValues(definition))
+    }
+    return errs.errorOrNil()
+}
 
-    for bean := range ctx.container.iterate() {
-        isBeanSuitable :=
-            (dependency.hasQualifier &&
-                bean.definition.isSuitableForDependencyByQualifier(dependency) &&
-                bean.definition.isSuitableForDependencyByType(dependency)) ||
-                (!dependency.hasQualifier &&
-                    bean.definition.isSuitableForDependencyByType(dependency))
-        if isBeanFound && isBeanSuitable {
-            return reflect.Value{}, errors.New("Two or more beans are suitable for dependency " + dependency.String())
+func (ctx *contextImpl) validateDefinitionValues(definition *beanDefinition) error {
+    errs := &MultiError{}
+    for _, dependency := range definition.allDependencies() {
+        if !dependency.isValue {
+            continue
         }
-        if isBeanSuitable {
-            isBeanFound = true
-            foundBean = reflect.ValueOf(bean.instance)
+        violation := func(constraint string, message string) *ValidationError {
+            return &ValidationError{
+                Bean:       definition.shortString(),
+                Field:      dependency.name,
+                Property:   dependency.qualifier,
+                Constraint: constraint,
+                Message:    message,
+            }
+        }
+        propertyValue, e := resolvePropertyValue(ctx.environment, dependency)
+        if e != nil {
+            errs.append(violation(ConstraintRequired, e.Error()))
+            continue
+        }
+        value, e := dependency.parsePropertyValue(propertyValue)
+        if e != nil {
+            errs.append(violation(dependency.type_.String(), e.Error()))
+            continue
+        }
+        for _, constraint := range dependency.constraints {
+            if message := constraint.check(propertyValue, value); message != "" {
+                errs.append(violation(constraint.String(), message))
+            }
         }
     }
+    return errs.errorOrNil()
+}
 
-    if !isBeanFound {
-        return reflect.Value{}, errors.New("Cannot find bean for dependency " + dependency.String())
+func (ctx *contextImpl) findDependencyBeanValue(
+    res *resolution,
+    requester *beanDefinition,
+    dependency *dependency,
+) (reflect.Value, error) {
+    if dependency.isCollection {
+        return ctx.collectDependencyBeans(res, dependency)
+    }
+    var found []*beanDefinition
+    for _, definition := range res.index.find(dependency) {
+        if res.hasBean(definition) {
+            found = append(found, definition)
+        }
     }
-    return foundBean, nil
+
+    switch len(found) {
+    case 0:
+        // request scoped beans are found only within the request scope
+        if definition := ctx.findRequestScopedDefinition(dependency); definition != nil {
+            return reflect.Value{}, errors.New("Request scoped " + definition.shortString() +
+                " cannot be injected into " + definitionName(requester) + ", use Provider instead")
+        }
+        return reflect.Value{}, newMissingBeanError(requester, dependency, ctx.beanDefinitions.ls)
+    case 1:
+        instance, e := ctx.getDefinitionInstance(res, found[0])
+        if e != nil {
+            return reflect.Value{}, e
+        }
+        return reflect.ValueOf(instance), nil
+    }
+    return reflect.Value{}, newAmbiguousBeanError(requester, dependency, found)
 }
 
-func (ctx *contextImpl) findDependencyValue(dependency *dependency) (reflect.Value, error) {
+// Finds the value for the dependency of the requester definition.
+// The requester is used in errors only and can be nil.
+func (ctx *contextImpl) findDependencyValue(
+    res *resolution,
+    requester *beanDefinition,
+    dependency *dependency,
+) (reflect.Value, error) {
     if dependency.isValue {
-        return ctx.getDependencyValueInstance(dependency)
+        return ctx.getDependencyValueInstance(res, requester, dependency)
     }
     if dependency.isBean {
-        return ctx.findDependencyBeanValue(dependency)
+        value, e := ctx.findDependencyBeanValue(res, requester, dependency)
+        if e != nil {
+            return reflect.Value{}, e
+        }
+        return dependency.wrap(value), nil
+    }
+    if dependency.isProvider {
+        return reflect.ValueOf(&providerImpl{
+            ctx:        ctx,
+            requester:  requester,
+            dependency: dependency,
+        }), nil
     }
     return reflect.Value{}, errors.New("Invalid dependency " + dependency.String())
 }
 
-// TODO: refactor this function
-func (ctx *contextImpl) instantiateBeans() error {
+func (ctx *contextImpl) instantiateBeans(include func(definition *beanDefinition) bool) error {
     ctx.logger.Info("Instantiation the beans...")
 
-    for definition := range ctx.graph.iterate() {
-        var paramValues []reflect.Value
-
-        var paramIndex uint16 = 0
-        for _, paramType := range definition.paramTypes {
-
-            if paramType.Kind() == reflect.Struct {
-                if !(definition.factory.isMethod && paramIndex == 0) {
-                    structParam := reflect.New(paramType).Elem()
-                    for i := 0; i < paramType.NumField(); i++ {
-                        dependency := definition.dependencies[paramIndex]
-                        instance, e := ctx.findDependencyValue(dependency)
-                        if e != nil {
-                            return e
-                        }
-                        structParam.FieldByName(dependency.name).Set(instance)
-                        paramIndex += 1
-                    }
-                    paramValues = append(paramValues, structParam)
-                    continue
-                }
-            }
+    // Property sources and decryptors go first, so the values injected
+    // into all the beans can be validated before any other factory is called
+    errs := &MultiError{}
+    e := ctx.instantiateDefinitions(func(definition *beanDefinition) bool {
+        return definition.isInfrastructure()
+    })
+    if e != nil {
+        if ctx.failFast {
+            return e
+        }
+        errs.append(e)
+    }
+    e = ctx.validateValues(include)
+    if e != nil {
+        errs.append(e)
+        return errs
+    }
+    errs.append(ctx.instantiateDefinitions(func(definition *beanDefinition) bool {
+        return !definition.isInfrastructure() && include(definition)
+    }))
+    return errs.errorOrNil()
+}
 
-            dependency := definition.dependencies[paramIndex]
-            instance, e := ctx.findDependencyValue(dependency)
-            if e != nil {
+// Instantiates the definitions accepted by the filter. Unless failFast is set,
+// continues after an error, skipping the definitions that depend on the failed ones.
+func (ctx *contextImpl) instantiateDefinitions(filter func(definition *beanDefinition) bool) error {
+    errs := &MultiError{}
+    for _, definition := range ctx.graph.sortedDefinitions() {
+        if !filter(definition) {
+            continue
+        }
+        if ctx.graph.dependsOnFailed(definition.graphIndex) {
+            ctx.graph.failed[definition.graphIndex] = true
+            continue
+        }
+        if definition.isCreatedOnLookup() {
+            continue
+        }
+        bean, e := ctx.instantiateDefinition(definition)
+        if e == nil {
+            e = ctx.addBeanToContainers(bean)
+        }
+        if e != nil {
+            if ctx.failFast {
                 return e
             }
-            paramValues = append(paramValues, instance)
-            paramIndex += 1
+            errs.append(e)
+            ctx.graph.failed[definition.graphIndex] = true
         }
+    }
+    return errs.errorOrNil()
+}
 
-        bean, e := definition.createBean(paramValues)
-        if e != nil {
-            return e
+// Must be called with the build lock held
+func (ctx *contextImpl) instantiateDefinition(definition *beanDefinition) (*bean, error) {
+    res := ctx.newResolution(nil)
+    paramValues, e := ctx.resolveParams(res, definition)
+    if e != nil {
+        return nil, e
+    }
+    return definition.createBean(res, paramValues)
+}
+
+// Resolves the values of all the factory params of the definition.
+// Request scoped dependencies are taken from the request scope of the resolution.
+// TODO: refactor this function
+func (ctx *contextImpl) resolveParams(res *resolution, definition *beanDefinition) ([]reflect.Value, error) {
+    var paramValues []reflect.Value
+
+    var paramIndex uint16 = 0
+    for _, paramType := range definition.paramTypes {
+
+        if paramType.Kind() == reflect.Struct && !isQualifiedWrapper(paramType) {
+            if !(definition.factory.isMethod && paramIndex == 0) {
+                structParam := reflect.New(paramType).Elem()
+                for i := 0; i < paramType.NumField(); i++ {
+                    dependency := definition.dependencies[paramIndex]
+                    instance, e := ctx.findDependencyValue(res, definition, dependency)
+                    if e != nil {
+                        return nil, e
+                    }
+                    structParam.FieldByName(dependency.name).Set(instance)
+                    paramIndex += 1
+                }
+                paramValues = append(paramValues, structParam)
+                continue
+            }
         }
-        e = ctx.addBeanToContainers(bean)
+
+        dependency := definition.dependencies[paramIndex]
+        instance, e := ctx.findDependencyValue(res, definition, dependency)
         if e != nil {
-            return e
+            return nil, e
         }
+        paramValues = append(paramValues, instance)
+        paramIndex += 1
     }
-    return nil
+
+    return paramValues, nil
 }
 
 func (ctx *contextImpl) runPostProcessors() error {
-    for pp := range ctx.postProcessors.iterate() {
+    errs := &MultiError{}
+    for _, pp := range ctx.postProcessors.all() {
         e := pp.PostProcess(ctx)
         if e != nil {
-            return e
+            if ctx.failFast {
+                return e
+            }
+            errs.append(e)
         }
     }
-    return nil
+    return errs.errorOrNil()
 }
 
 //endregion
@@ -0,0 +1,153 @@
+package pp_ioc
+
+import (
+    log "github.com/sirupsen/logrus"
+    "reflect"
+    "strconv"
+    "testing"
+)
+
+var benchmarkSizes = []int{1000, 5000, 10000}
+
+type benchmarkBean struct {
+    dependency *benchmarkBean
+}
+
+type benchmarkService struct {
+    root *benchmarkBean
+}
+
+// Binds the binary tree of the beans, each one depends on its parent,
+// and the service of the unique type
+type benchmarkConfiguration struct {
+    size int
+}
+
+func (c *benchmarkConfiguration) Bind(ctx Context) error {
+    ctx.NewBinder().
+        Qualifiers(benchmarkBeanName(0)).
+        Factory(func() *benchmarkBean {
+            return &benchmarkBean{}
+        })
+    for i := 1; i < c.size; i++ {
+        ctx.NewBinder().
+            Qualifiers(benchmarkBeanName(i)).
+            Factory(benchmarkFactory(benchmarkBeanName((i - 1) / 2)))
+    }
+    ctx.NewBinder().
+        Qualifiers("service").
+        Factory(func(p struct {
+            Root *benchmarkBean `qualifier:"bean-0"`
+        }) *benchmarkService {
+            return &benchmarkService{root: p.Root}
+        })
+    return nil
+}
+
+func benchmarkBeanName(i int) string {
+    return "bean-" + strconv.Itoa(i)
+}
+
+// Creates the factory injecting the bean with the qualifier,
+// the param struct is built at runtime, since its tag is not known statically
+func benchmarkFactory(qualifier string) interface{} {
+    beanType := reflect.TypeOf((*benchmarkBean)(nil))
+    paramType := reflect.StructOf([]reflect.StructField{{
+        Name: "Dependency",
+        Type: beanType,
+        Tag:  reflect.StructTag(TagQualifier + `:"` + qualifier + `"`),
+    }})
+    factoryType := reflect.FuncOf([]reflect.Type{paramType}, []reflect.Type{beanType}, false)
+    return reflect.MakeFunc(factoryType, func(args []reflect.Value) []reflect.Value {
+        dependency := args[0].Field(0).Interface().(*benchmarkBean)
+        return []reflect.Value{reflect.ValueOf(&benchmarkBean{dependency: dependency})}
+    }).Interface()
+}
+
+func newBenchmarkContext(b *testing.B, size int) Context {
+    b.Helper()
+    ctx := NewContext()
+    if e := ctx.Register(&benchmarkConfiguration{size: size}); e != nil {
+        b.Fatal(e)
+    }
+    return ctx
+}
+
+// Logging every bean would dominate the measurements
+func quietLogs(b *testing.B) {
+    level := log.GetLevel()
+    log.SetLevel(log.WarnLevel)
+    b.Cleanup(func() {
+        log.SetLevel(level)
+    })
+}
+
+func BenchmarkBuild(b *testing.B) {
+    quietLogs(b)
+    for _, size := range benchmarkSizes {
+        b.Run(strconv.Itoa(size), func(b *testing.B) {
+            for i := 0; i < b.N; i++ {
+                b.StopTimer()
+                ctx := newBenchmarkContext(b, size)
+                b.StartTimer()
+                if e := ctx.Build(); e != nil {
+                    b.Fatal(e)
+                }
+            }
+        })
+    }
+}
+
+func BenchmarkGetBeanByName(b *testing.B) {
+    quietLogs(b)
+    for _, size := range benchmarkSizes {
+        b.Run(strconv.Itoa(size), func(b *testing.B) {
+            ctx := newBenchmarkContext(b, size)
+            if e := ctx.Build(); e != nil {
+                b.Fatal(e)
+            }
+            name := benchmarkBeanName(size - 1)
+            b.ResetTimer()
+            for i := 0; i < b.N; i++ {
+                if _, e := ctx.GetBeanByName(name); e != nil {
+                    b.Fatal(e)
+                }
+            }
+        })
+    }
+}
+
+func BenchmarkGetBeanByType(b *testing.B) {
+    quietLogs(b)
+    serviceType := reflect.TypeOf((*benchmarkService)(nil))
+    for _, size := range benchmarkSizes {
+        b.Run(strconv.Itoa(size), func(b *testing.B) {
+            ctx := newBenchmarkContext(b, size)
+            if e := ctx.Build(); e != nil {
+                b.Fatal(e)
+            }
+            b.ResetTimer()
+            for i := 0; i < b.N; i++ {
+                if _, e := ctx.GetBeanByType(serviceType); e != nil {
+                    b.Fatal(e)
+                }
+            }
+        })
+    }
+}
+
+func BenchmarkValidate(b *testing.B) {
+    quietLogs(b)
+    for _, size := range benchmarkSizes {
+        b.Run(strconv.Itoa(size), func(b *testing.B) {
+            for i := 0; i < b.N; i++ {
+                b.StopTimer()
+                ctx := newBenchmarkContext(b, size)
+                b.StartTimer()
+                if e := ctx.Validate(); e != nil {
+                    b.Fatal(e)
+                }
+            }
+        })
+    }
+}
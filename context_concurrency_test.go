@@ -0,0 +1,395 @@
+package pp_ioc
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// These tests are meant to be run with the race detector: go test -race
+
+type testPropertySource struct {
+    mutex      sync.RWMutex
+    properties map[string]string
+}
+
+func (s *testPropertySource) Get(key string) (string, error) {
+    s.mutex.RLock()
+    defer s.mutex.RUnlock()
+    if v, ok := s.properties[key]; ok {
+        return v, nil
+    }
+    return "", errors.New("Cannot find property " + key)
+}
+
+func (s *testPropertySource) GetAll() map[string]string {
+    s.mutex.RLock()
+    defer s.mutex.RUnlock()
+    return copyProperties(s.properties)
+}
+
+func (s *testPropertySource) set(key string, value string) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    s.properties[key] = value
+}
+
+type testRepository struct {
+    url string
+}
+
+type testRequest struct {
+    repository *testRepository
+}
+
+type testJob struct {
+    repository *testRepository
+}
+
+type testService struct {
+    repository *testRepository
+    requests   Provider
+}
+
+type testPrototype struct {
+    repository *testRepository
+}
+
+type testLazy struct {
+    repository *testRepository
+}
+
+type testJobScope struct{}
+
+func (testJobScope) Get(name string, factory func() (interface{}, error)) (interface{}, error) {
+    return factory()
+}
+
+func (testJobScope) Remove(name string) interface{} {
+    return nil
+}
+
+type concurrencyConfiguration struct {
+    properties *testPropertySource
+    lazyCalls  int32
+}
+
+func (c *concurrencyConfiguration) Bind(ctx Context) error {
+    ctx.NewPropertySourceBinder().
+        Qualifiers("testProperties").
+        Factory(func() *testPropertySource {
+            return c.properties
+        })
+    ctx.NewBinder().
+        Qualifiers("repository").
+        Scope(ScopeRefresh).
+        Factory(func(p struct {
+            Url string `value:"${db.url}"`
+        }) *testRepository {
+            return &testRepository{url: p.Url}
+        })
+    // the factory looks the context up, which must not deadlock with a pending refresh
+    ctx.NewBinder().
+        Qualifiers("request").
+        Scope(ScopeRequest).
+        Factory(func(appCtx Context) (*testRequest, error) {
+            // gives the refresh time to wait for the lock
+            time.Sleep(time.Millisecond)
+            repository, e := appCtx.GetBeanByName("repository")
+            if e != nil {
+                return nil, e
+            }
+            return &testRequest{repository: repository.(*testRepository)}, nil
+        })
+    ctx.NewBinder().
+        Qualifiers("job").
+        CustomScope("job").
+        Factory(func(p struct {
+            Repository *testRepository `qualifier:"repository"`
+        }) *testJob {
+            return &testJob{repository: p.Repository}
+        })
+    ctx.NewBinder().
+        Qualifiers("prototype").
+        Scope(ScopePrototype).
+        Factory(func(p struct {
+            Repository *testRepository `qualifier:"repository"`
+        }) *testPrototype {
+            return &testPrototype{repository: p.Repository}
+        })
+    ctx.NewBinder().
+        Qualifiers("lazy").
+        Lazy().
+        Factory(func(p struct {
+            Repository *testRepository `qualifier:"repository"`
+        }) *testLazy {
+            atomic.AddInt32(&c.lazyCalls, 1)
+            // gives the concurrent lookups time to race for the creation
+            time.Sleep(time.Millisecond)
+            return &testLazy{repository: p.Repository}
+        })
+    ctx.NewBinder().
+        Qualifiers("service").
+        Factory(func(p struct {
+            Repository *testRepository `qualifier:"repository"`
+            Requests   Provider        `qualifier:"request"`
+        }) *testService {
+            return &testService{repository: p.Repository, requests: p.Requests}
+        })
+    return nil
+}
+
+func newConcurrencyContext(t *testing.T) (*contextImpl, *testPropertySource) {
+    t.Helper()
+    ctx, configuration := newConcurrencyContextWithScope(t, testJobScope{})
+    return ctx, configuration.properties
+}
+
+func newConcurrencyContextWithScope(t *testing.T, scope Scope) (*contextImpl, *concurrencyConfiguration) {
+    t.Helper()
+    configuration := &concurrencyConfiguration{
+        properties: &testPropertySource{properties: map[string]string{"db.url": "url-0"}},
+    }
+    ctx := NewContext().(*contextImpl)
+    if e := ctx.RegisterScope("job", scope); e != nil {
+        t.Fatal(e)
+    }
+    if e := ctx.Register(configuration); e != nil {
+        t.Fatal(e)
+    }
+    if e := ctx.Build(); e != nil {
+        t.Fatal(e)
+    }
+    t.Cleanup(func() {
+        if e := ctx.Close(); e != nil {
+            t.Error(e)
+        }
+    })
+    return ctx, configuration
+}
+
+// Lookups are rejected while the context is refreshing, any other error is a failure
+func isNotBuiltError(e error) bool {
+    return strings.Contains(e.Error(), "the context is not built")
+}
+
+// Runs the lookup in several goroutines until the returned function is called,
+// which waits for them and reports the unexpected errors
+func runConcurrently(t *testing.T, goroutines int, lookup func() error) func() {
+    stop := make(chan struct{})
+    errs := make(chan error, goroutines)
+    var wg sync.WaitGroup
+    for i := 0; i < goroutines; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for {
+                select {
+                case <-stop:
+                    return
+                default:
+                }
+                if e := lookup(); e != nil && !isNotBuiltError(e) {
+                    errs <- e
+                    return
+                }
+            }
+        }()
+    }
+    return func() {
+        close(stop)
+        wg.Wait()
+        close(errs)
+        for e := range errs {
+            t.Error(e)
+        }
+    }
+}
+
+func getRequestByProvider(ctx Context) error {
+    service, e := ctx.GetBeanByName("service")
+    if e != nil {
+        return e
+    }
+    requestCtx, end := WithScope(context.Background())
+    defer func() {
+        _ = end()
+    }()
+    request, e := service.(*testService).requests.Get(requestCtx)
+    if e != nil {
+        return e
+    }
+    if _, ok := request.(*testRequest); !ok {
+        return errors.New("Unexpected request bean " + reflect.TypeOf(request).String())
+    }
+    return nil
+}
+
+func TestConcurrentLookupsDuringRefresh(t *testing.T) {
+    ctx, _ := newConcurrencyContext(t)
+    serviceType := reflect.TypeOf((*testService)(nil))
+
+    stopByType := runConcurrently(t, 4, func() error {
+        _, e := ctx.GetBeanByType(serviceType)
+        return e
+    })
+    stopByName := runConcurrently(t, 4, func() error {
+        _, e := ctx.GetBeanByName("service")
+        return e
+    })
+    stopProvider := runConcurrently(t, 4, func() error {
+        return getRequestByProvider(ctx)
+    })
+    stopScoped := runConcurrently(t, 4, func() error {
+        _, e := ctx.GetBeanByName("job")
+        return e
+    })
+    stopCreated := runConcurrently(t, 4, func() error {
+        if _, e := ctx.GetBeanByName("prototype"); e != nil {
+            return e
+        }
+        _, e := ctx.GetBeanByName("lazy")
+        return e
+    })
+
+    for i := 0; i < 20; i++ {
+        if e := ctx.Refresh(); e != nil {
+            t.Fatal(e)
+        }
+    }
+    stopByType()
+    stopByName()
+    stopProvider()
+    stopScoped()
+    stopCreated()
+}
+
+func TestConcurrentLookupsDuringPropertiesChange(t *testing.T) {
+    ctx, properties := newConcurrencyContext(t)
+
+    stop := runConcurrently(t, 8, func() error {
+        repository, e := ctx.GetBeanByName("repository")
+        if e != nil {
+            return e
+        }
+        if url := repository.(*testRepository).url; !strings.HasPrefix(url, "url-") {
+            return errors.New("Unexpected url " + url)
+        }
+        _, e = ctx.GetBeanByName("job")
+        return e
+    })
+    for i := 1; i <= 50; i++ {
+        properties.set("db.url", "url-"+strconv.Itoa(i))
+        ctx.onPropertiesChanged(PropertiesChanged{Keys: []string{"db.url"}})
+    }
+    stop()
+
+    repository, e := ctx.GetBeanByName("repository")
+    if e != nil {
+        t.Fatal(e)
+    }
+    if url := repository.(*testRepository).url; url != "url-50" {
+        t.Errorf("Expected the rebuilt repository with url-50, got %s", url)
+    }
+}
+
+func TestScopedFactoryLooksUpContextDuringRefresh(t *testing.T) {
+    ctx, _ := newConcurrencyContext(t)
+    done := make(chan struct{})
+    stop := runConcurrently(t, 8, func() error {
+        return getRequestByProvider(ctx)
+    })
+    go func() {
+        defer close(done)
+        for deadline := time.Now().Add(200 * time.Millisecond); time.Now().Before(deadline); {
+            // lets the lookups into the built context
+            time.Sleep(time.Millisecond)
+            if e := ctx.Refresh(); e != nil {
+                t.Error(e)
+                return
+            }
+        }
+    }()
+    select {
+    case <-done:
+    case <-time.After(30 * time.Second):
+        // the cleanup would wait for the deadlocked refresh, the panic dumps the goroutines instead
+        panic("Refresh is deadlocked with the lookups")
+    }
+    stop()
+}
+
+func TestConcurrentPrototypeLookups(t *testing.T) {
+    ctx, _ := newConcurrencyContext(t)
+    var mutex sync.Mutex
+    instances := map[*testPrototype]bool{}
+    lookups := 0
+    stop := runConcurrently(t, 8, func() error {
+        prototype, e := ctx.GetBeanByName("prototype")
+        if e != nil {
+            return e
+        }
+        mutex.Lock()
+        defer mutex.Unlock()
+        instances[prototype.(*testPrototype)] = true
+        lookups++
+        return nil
+    })
+    time.Sleep(50 * time.Millisecond)
+    stop()
+
+    if lookups == 0 || len(instances) != lookups {
+        t.Errorf("Expected the new prototype for each of %d lookups, got %d instances", lookups, len(instances))
+    }
+}
+
+func TestConcurrentLazySingletonCreation(t *testing.T) {
+    ctx, configuration := newConcurrencyContextWithScope(t, testJobScope{})
+    if calls := atomic.LoadInt32(&configuration.lazyCalls); calls != 0 {
+        t.Fatalf("Expected the lazy singleton not to be created by Build, got %d calls", calls)
+    }
+
+    lookupAll := func() map[*testLazy]bool {
+        var mutex sync.Mutex
+        instances := map[*testLazy]bool{}
+        var wg sync.WaitGroup
+        for i := 0; i < 8; i++ {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                lazy, e := ctx.GetBeanByName("lazy")
+                if e != nil {
+                    t.Error(e)
+                    return
+                }
+                mutex.Lock()
+                instances[lazy.(*testLazy)] = true
+                mutex.Unlock()
+            }()
+        }
+        wg.Wait()
+        return instances
+    }
+
+    if instances := lookupAll(); len(instances) != 1 {
+        t.Errorf("Expected the only lazy singleton, got %d instances", len(instances))
+    }
+    if calls := atomic.LoadInt32(&configuration.lazyCalls); calls != 1 {
+        t.Errorf("Expected the factory to be called once, got %d calls", calls)
+    }
+    if e := ctx.Refresh(); e != nil {
+        t.Fatal(e)
+    }
+    if instances := lookupAll(); len(instances) != 1 {
+        t.Errorf("Expected the only lazy singleton after the refresh, got %d instances", len(instances))
+    }
+    if calls := atomic.LoadInt32(&configuration.lazyCalls); calls != 2 {
+        t.Errorf("Expected the factory to be called once more after the refresh, got %d calls", calls)
+    }
+}
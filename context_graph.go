@@ -8,55 +8,133 @@ import (
 )
 
 type contextGraph struct {
-    logger logCtx.NamedLogger
-    graph  g.OrientedGraph
-    sorted []int
+    logger      logCtx.NamedLogger
+    graph       g.OrientedGraph
+    index       *definitionIndex
+    sorted      []*beanDefinition
+    definitions []*beanDefinition
+    edges       []*graphEdge
+    dependents  map[int][]int
+    outgoing    map[int][]*graphEdge
+    failed      map[int]bool
+}
+
+// Edge from the dependent definition to its dependency
+type graphEdge struct {
+    from       int
+    to         int
+    dependency *dependency
 }
 
 func newContextGraph() *contextGraph {
     return &contextGraph{
-        logger: logCtx.Get("IOC.ContextGraph"),
-        graph:  g.NewOrientedGraph(),
+        logger:     logCtx.Get("IOC.ContextGraph"),
+        graph:      g.NewOrientedGraph(),
+        dependents: map[int][]int{},
+        outgoing:   map[int][]*graphEdge{},
+        failed:     map[int]bool{},
     }
 }
 
 func (ctxG *contextGraph) build(beanDefinitions *beanDefinitionContainer) error {
+    if e := ctxG.addGraphNodesAndEdges(beanDefinitions); e != nil {
+        return e
+    }
+    return ctxG.sort()
+}
+
+// Adds the edges of all the resolvable dependencies, even if some are missing,
+// and returns the errors of the missing ones
+func (ctxG *contextGraph) addGraphNodesAndEdges(beanDefinitions *beanDefinitionContainer) error {
     ctxG.logger.Info("Building the dependency graph...")
+    ctxG.index = newDefinitionIndex(beanDefinitions)
 
     e := ctxG.addGraphNodes(beanDefinitions)
     if e != nil {
         return e
     }
-    e = ctxG.addGraphEdges(beanDefinitions)
+    return ctxG.addGraphEdges(beanDefinitions)
+}
+
+// Sorts the definitions topologically, fails if the graph has a cycle
+func (ctxG *contextGraph) sort() error {
+    sorted, e := ctxG.graph.TopologicalSort()
     if e != nil {
         return e
     }
-    ctxG.sorted, e = ctxG.graph.TopologicalSort()
-    if e != nil {
-        return e
+    ctxG.sorted = make([]*beanDefinition, 0, len(sorted))
+    for _, ind := range sorted {
+        data, _ := ctxG.graph.GetDataForIndex(ind)
+        ctxG.sorted = append(ctxG.sorted, data.(*beanDefinition))
     }
     return nil
 }
 
-func (ctxG *contextGraph) iterate() <-chan *beanDefinition {
-    c := make(chan *beanDefinition)
-    go func() {
-        for _, ind := range ctxG.sorted {
-            data, _ := ctxG.graph.GetDataForIndex(ind)
-            c <- data.(*beanDefinition)
+// Returns the definitions in the topological order, callers must not modify the slice
+func (ctxG *contextGraph) sortedDefinitions() []*beanDefinition {
+    return ctxG.sorted
+}
+
+// Returns in the topological order the given definitions
+// and all the definitions which depend on them, directly or transitively
+func (ctxG *contextGraph) dependentsOf(indexes []int) []*beanDefinition {
+    affected := map[int]bool{}
+    queue := append([]int{}, indexes...)
+    for len(queue) > 0 {
+        ind := queue[0]
+        queue = queue[1:]
+        if affected[ind] {
+            continue
         }
-        close(c)
-    }()
-    return c
+        affected[ind] = true
+        queue = append(queue, ctxG.dependents[ind]...)
+    }
+
+    var res []*beanDefinition
+    for _, definition := range ctxG.sorted {
+        if affected[definition.graphIndex] {
+            res = append(res, definition)
+        }
+    }
+    return res
+}
+
+// Returns the given definitions and all their dependencies, direct or transitive
+func (ctxG *contextGraph) collectDependencies(indexes []int) map[int]bool {
+    included := map[int]bool{}
+    queue := append([]int{}, indexes...)
+    for len(queue) > 0 {
+        ind := queue[0]
+        queue = queue[1:]
+        if included[ind] {
+            continue
+        }
+        included[ind] = true
+        for _, edge := range ctxG.outgoing[ind] {
+            queue = append(queue, edge.to)
+        }
+    }
+    return included
+}
+
+// Checks whether any direct dependency of the definition has failed to instantiate
+func (ctxG *contextGraph) dependsOnFailed(index int) bool {
+    for _, edge := range ctxG.outgoing[index] {
+        if ctxG.failed[edge.to] {
+            return true
+        }
+    }
+    return false
 }
 
 func (ctxG *contextGraph) addGraphNodes(beanDefinitions *beanDefinitionContainer) error {
-    for definition := range beanDefinitions.iterate() {
+    for _, definition := range beanDefinitions.all() {
         index, e := ctxG.graph.AddNode(definition)
         if e != nil {
             return errors.Wrap(e, "Cannot add binding key "+definition.shortString())
         }
         definition.updateGraphIndex(index)
+        ctxG.definitions = append(ctxG.definitions, definition)
         ctxG.logger.WithFields(log.Fields{
             "beanDef": definition.String(),
             "index":   index,
@@ -66,101 +144,81 @@ func (ctxG *contextGraph) addGraphNodes(beanDefinitions *beanDefinitionContainer
 }
 
 func (ctxG *contextGraph) addGraphEdges(beanDefinitions *beanDefinitionContainer) error {
-    for beanDefinition := range beanDefinitions.iterate() {
-        for _, dependency := range beanDefinition.dependencies {
+    errs := &MultiError{}
+    for _, beanDefinition := range beanDefinitions.all() {
+        for _, dependency := range beanDefinition.allDependencies() {
             if !dependency.isBean {
                 continue
             }
-            from, e := findDefinitionIndexForDefinition(beanDefinitions, beanDefinition)
+            toList, e := ctxG.findDefinitionIndexesForDependency(beanDefinitions, beanDefinition, dependency)
             if e != nil {
-                return e
-            }
-            toList, e := findDefinitionIndexesForDependency(beanDefinitions, dependency)
-            if e != nil {
-                return e
+                // keep going to report every missing bean at once
+                errs.append(e)
+                continue
             }
             for _, to := range toList {
-                graphError := ctxG.graph.AddEdge(from, to)
-                if graphError != nil {
-                    return errors.Wrap(graphError, "Cannot add dependency for " + beanDefinition.shortString())
+                if e := ctxG.addEdge(beanDefinition, to, dependency); e != nil {
+                    return e
                 }
-                ctxG.logger.WithFields(log.Fields{
-                    "from":      beanDefinition.String(),
-                    "fromIndex": from,
-                    "to":        dependency.String(),
-                    "toIndex":   to,
-                }).Trace("Added dependency")
+            }
+        }
+        // interceptors are applied during instantiation, so they go first
+        for _, interceptor := range beanDefinition.interceptors {
+            dependency := newBeanDependency("interceptor", "", false, interceptor.key.type_, 0)
+            if e := ctxG.addEdge(beanDefinition, interceptor.graphIndex, dependency); e != nil {
+                return e
             }
         }
     }
-    return nil
+    return errs.errorOrNil()
 }
 
-func findDefinitionIndexesForDependency(
-    beanDefinitions *beanDefinitionContainer,
-    dependency *dependency,
-) (foundIndexes []int, e error) {
-    var isBeanDefinitionFound = false
-
-    for beanDefinition := range beanDefinitions.iterate() {
-        isBeanDefinitionSuitable :=
-            (dependency.hasQualifier &&
-                beanDefinition.isSuitableForDependencyByQualifier(dependency) &&
-                beanDefinition.isSuitableForDependencyByType(dependency)) ||
-                (!dependency.hasQualifier &&
-                    beanDefinition.isSuitableForDependencyByType(dependency))
-        if isBeanDefinitionSuitable {
-            isBeanDefinitionFound = true
-            foundIndexes = append(foundIndexes, beanDefinition.graphIndex)
-        }
+func (ctxG *contextGraph) addEdge(definition *beanDefinition, to int, dependency *dependency) error {
+    from := definition.graphIndex
+    graphError := ctxG.graph.AddEdge(from, to)
+    if graphError != nil {
+        return errors.Wrap(graphError, "Cannot add dependency for " + definition.shortString())
     }
-
-    if !isBeanDefinitionFound {
-        return nil, errors.New("Cannot find bean definition for dependency " + dependency.String())
+    ctxG.dependents[to] = append(ctxG.dependents[to], from)
+    edge := &graphEdge{
+        from:       from,
+        to:         to,
+        dependency: dependency,
     }
-    return foundIndexes, nil
+    ctxG.edges = append(ctxG.edges, edge)
+    ctxG.outgoing[from] = append(ctxG.outgoing[from], edge)
+    ctxG.logger.WithFields(log.Fields{
+        "from":      definition.String(),
+        "fromIndex": from,
+        "to":        dependency.String(),
+        "toIndex":   to,
+    }).Trace("Added dependency")
+    return nil
 }
 
-// TODO: refactor this function
-func findDefinitionIndexForDefinition(
+func (ctxG *contextGraph) findDefinitionIndexesForDependency(
     beanDefinitions *beanDefinitionContainer,
-    key *beanDefinition,
-) (int, error) {
-    found := false
-    ind := -1
-    for definition := range beanDefinitions.iterate() {
-        if foo1(definition.key.qualifiers, key.key.qualifiers) /*&& definition.key.type_ == key.key.type_*/ {
-            found = true
-            ind = definition.graphIndex
-            break
-        }
+    requester *beanDefinition,
+    dependency *dependency,
+) ([]int, error) {
+    found := ctxG.index.find(dependency)
+    if len(found) == 0 && !dependency.isCollection {
+        return nil, newMissingBeanError(requester, dependency, beanDefinitions.ls)
     }
-    if !found {
-        return -1, errors.New("Cannot find bean definition " + key.shortString())
+    foundIndexes := make([]int, 0, len(found))
+    for _, definition := range found {
+        for _, alias := range definition.aliasesUsedBy(dependency) {
+            warnDeprecatedAlias(ctxG.logger, alias, definition)
+        }
+        foundIndexes = append(foundIndexes, definition.graphIndex)
     }
-    return ind, nil
+    return foundIndexes, nil
 }
 
-func foo1(names1 []string, names2 []string) bool {
-    if len(names1) != len(names2) {
-        return false
-    }
-    allFound := true
-    for _, name1 := range names1 {
-        found := false
-        for _, name2 := range names2 {
-            if name1 == name2 {
-                found = true
-                break
-            }
-        }
-        if !found {
-            allFound = false
-            break
-        }
-    }
-    if !allFound {
-        return false
-    }
-    return true
+func isDefinitionSuitable(beanDefinition *beanDefinition, dependency *dependency) bool {
+    return (dependency.isQualified() &&
+        beanDefinition.isSuitableForDependencyByQualifier(dependency) &&
+        beanDefinition.isSuitableForDependencyByType(dependency)) ||
+        (!dependency.isQualified() &&
+            beanDefinition.isSuitableForDependencyByType(dependency))
 }
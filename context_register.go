@@ -0,0 +1,66 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+    log "github.com/sirupsen/logrus"
+    "reflect"
+    "strings"
+)
+
+func (ctx *contextImpl) Register(configurations ...Configuration) error {
+    if ctx.bound {
+        return errors.New("Configurations cannot be registered after the context is bound")
+    }
+    for _, configuration := range configurations {
+        if e := ctx.register(configuration, nil); e != nil {
+            return e
+        }
+    }
+    return nil
+}
+
+// Registers the imports of the configuration and then the configuration itself.
+// The path contains the names of the importing configurations.
+func (ctx *contextImpl) register(configuration Configuration, path []string) error {
+    if configuration == nil {
+        return errors.New("Invalid configuration: nil")
+    }
+    name := configurationName(configuration)
+    for _, importing := range path {
+        if importing == name {
+            return errors.New("Configuration import cycle: " +
+                strings.Join(append(path, name), " -> "))
+        }
+    }
+    if registered, ok := ctx.modules[name]; ok {
+        // the same module may be imported by several modules, the name and the type identify it
+        if reflect.TypeOf(registered) == reflect.TypeOf(configuration) {
+            return nil
+        }
+        return errors.New("Configurations of different types " + reflect.TypeOf(registered).String() +
+            " and " + reflect.TypeOf(configuration).String() + " have the same name " + name +
+            ", implement NamedConfiguration to tell them apart")
+    }
+
+    if importing, ok := configuration.(ImportingConfiguration); ok {
+        for _, imported := range importing.Imports() {
+            if e := ctx.register(imported, append(path, name)); e != nil {
+                return e
+            }
+        }
+    }
+
+    ctx.modules[name] = configuration
+    previousModule := ctx.currentModule
+    ctx.currentModule = name
+    defer func() {
+        ctx.currentModule = previousModule
+    }()
+    if e := configuration.Bind(ctx); e != nil {
+        return errors.Wrap(e, "Cannot register configuration "+name)
+    }
+    ctx.logger.WithFields(log.Fields{
+        "module": name,
+    }).Info("Configuration registered")
+    return nil
+}
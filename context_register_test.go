@@ -0,0 +1,78 @@
+package pp_ioc
+
+import (
+    "testing"
+)
+
+// Value configuration holding a func field, which is never deep equal to its copy
+type clockConfiguration struct {
+    now func() int64
+}
+
+func (c clockConfiguration) Bind(ctx Context) error {
+    ctx.NewBinder().
+        Qualifiers("clock").
+        Factory(func() *testClock {
+            return &testClock{now: c.now}
+        })
+    return nil
+}
+
+type testClock struct {
+    now func() int64
+}
+
+type clockUserConfiguration struct {
+    name string
+}
+
+func (c *clockUserConfiguration) Name() string {
+    return c.name
+}
+
+func (c *clockUserConfiguration) Imports() []Configuration {
+    return []Configuration{clockConfiguration{now: func() int64 { return 42 }}}
+}
+
+func (c *clockUserConfiguration) Bind(ctx Context) error {
+    return nil
+}
+
+type otherClockConfiguration struct{}
+
+func (c *otherClockConfiguration) Name() string {
+    return "pp_ioc.clockConfiguration"
+}
+
+func (c *otherClockConfiguration) Bind(ctx Context) error {
+    return nil
+}
+
+func TestSameModuleImportedTwiceIsRegisteredOnce(t *testing.T) {
+    ctx := NewContext()
+    e := ctx.Register(&clockUserConfiguration{name: "orders"}, &clockUserConfiguration{name: "payments"})
+    if e != nil {
+        t.Fatal(e)
+    }
+    if e := ctx.Build(); e != nil {
+        t.Fatal(e)
+    }
+    defer func() {
+        _ = ctx.Close()
+    }()
+    clock, e := ctx.GetBeanByName("clock")
+    if e != nil {
+        t.Fatal(e)
+    }
+    if now := clock.(*testClock).now(); now != 42 {
+        t.Errorf("Expected 42, got %d", now)
+    }
+}
+
+func TestDifferentModulesWithSameNameAreRejected(t *testing.T) {
+    ctx := NewContext()
+    e := ctx.Register(&clockUserConfiguration{name: "orders"}, &otherClockConfiguration{})
+    if e == nil {
+        t.Error("Expected the error of the same name")
+    }
+}
@@ -0,0 +1,82 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+)
+
+func (ctx *contextImpl) Validate() error {
+    ctx.buildMutex.Lock()
+    defer ctx.buildMutex.Unlock()
+    ctx.logger.Info("Validating the context...")
+    errs := &MultiError{}
+    if e := ctx.bindEverything(); e != nil {
+        errs.append(e)
+    }
+
+    // the graph of the context is left for Build, the missing beans are reported below
+    graph := newContextGraph()
+    _ = graph.addGraphNodesAndEdges(ctx.beanDefinitions)
+    for _, definition := range ctx.beanDefinitions.all() {
+        for _, dependency := range definition.allDependencies() {
+            if dependency.isBean {
+                errs.append(ctx.validateBeanDependency(graph.index, definition, dependency))
+            }
+            if dependency.isValue {
+                errs.append(validateDefaultValue(definition, dependency))
+            }
+        }
+    }
+    // the cycles among the resolvable dependencies
+    errs.append(graph.sort())
+    return errs.errorOrNil()
+}
+
+func (ctx *contextImpl) validateBeanDependency(
+    index *definitionIndex,
+    definition *beanDefinition,
+    dependency *dependency,
+) error {
+    candidates := index.find(dependency)
+    if dependency.isCollection {
+        return nil // may be empty
+    }
+    switch len(candidates) {
+    case 0:
+        return newMissingBeanError(definition, dependency, ctx.beanDefinitions.ls)
+    case 1:
+        if candidates[0].scope == ScopeRequest && definition.scope != ScopeRequest {
+            return errors.New("Request scoped " + candidates[0].shortString() +
+                " cannot be injected into " + definition.shortString() + ", use Provider instead")
+        }
+        return nil
+    }
+    return newAmbiguousBeanError(definition, dependency, candidates)
+}
+
+// Checks that the default value can be parsed and satisfies the constraints
+func validateDefaultValue(definition *beanDefinition, dependency *dependency) error {
+    if !dependency.valueProvider.hasDefault {
+        return nil
+    }
+    errs := &MultiError{}
+    violation := func(constraint string, message string) *ValidationError {
+        return &ValidationError{
+            Bean:       definition.shortString(),
+            Field:      dependency.name,
+            Property:   dependency.qualifier,
+            Constraint: constraint,
+            Message:    "default value: " + message,
+        }
+    }
+    defaultValue := dependency.valueProvider.defaultValue
+    value, e := dependency.parsePropertyValue(defaultValue)
+    if e != nil {
+        return violation(dependency.type_.String(), e.Error())
+    }
+    for _, constraint := range dependency.constraints {
+        if message := constraint.check(defaultValue, value); message != "" {
+            errs.append(violation(constraint.String(), message))
+        }
+    }
+    return errs.errorOrNil()
+}
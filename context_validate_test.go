@@ -0,0 +1,57 @@
+package pp_ioc
+
+import (
+    "strings"
+    "testing"
+)
+
+type testA struct{}
+
+type testB struct{}
+
+type testPort struct{}
+
+type brokenConfiguration struct{}
+
+func (c *brokenConfiguration) Bind(ctx Context) error {
+    ctx.NewBinder().
+        Qualifiers("a").
+        Factory(func(p struct {
+            B *testB `qualifier:"b"`
+        }) *testA {
+            return &testA{}
+        })
+    ctx.NewBinder().
+        Qualifiers("b").
+        Factory(func(p struct {
+            A *testA `qualifier:"a"`
+        }) *testB {
+            return &testB{}
+        })
+    ctx.NewBinder().
+        Qualifiers("port").
+        Factory(func(p struct {
+            Port int `value:"${server.port:http}"`
+        }) *testPort {
+            return &testPort{}
+        })
+    return nil
+}
+
+func TestValidateReportsCyclesWithOtherErrors(t *testing.T) {
+    ctx := NewContext()
+    if e := ctx.Register(&brokenConfiguration{}); e != nil {
+        t.Fatal(e)
+    }
+    e := ctx.Validate()
+    if e == nil {
+        t.Fatal("Expected the validation errors")
+    }
+    multiError, ok := e.(*MultiError)
+    if !ok || len(multiError.Errors) != 2 {
+        t.Fatalf("Expected the cycle and the invalid default value, got %v", e)
+    }
+    if !strings.Contains(e.Error(), "server.port") {
+        t.Errorf("Expected the invalid default value, got %v", e)
+    }
+}
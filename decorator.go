@@ -0,0 +1,101 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+    log "github.com/sirupsen/logrus"
+    "reflect"
+    "sort"
+)
+
+// Attaches the decorator to the decorated definition. The decorator is not
+// a definition itself: its dependencies become the dependencies of the decorated
+// one, so the decorated instance is not a graph edge and cannot form a cycle.
+func (ctx *contextImpl) bindDecorator(binder *Binder) error {
+    factory := binder.beanFactory
+    if e := ctx.beanFactoryValidator.validate(factory); e != nil {
+        return e
+    }
+    if len(factory._inParamTypes) == 0 || factory._inParamTypes[0].Kind() == reflect.Struct {
+        return errors.New("Invalid decorator factory: the first IN parameter " +
+            "must be the decorated interface or pointer")
+    }
+
+    var found []*beanDefinition
+    for _, definition := range ctx.beanDefinitions.all() {
+        if isDefinitionSuitable(definition, binder.decorates) {
+            found = append(found, definition)
+        }
+    }
+    switch len(found) {
+    case 0:
+        return newMissingBeanError(nil, binder.decorates, ctx.beanDefinitions.ls)
+    case 1:
+    default:
+        return newAmbiguousBeanError(nil, binder.decorates, found)
+    }
+    target := found[0]
+    if !target.key.type_.AssignableTo(factory._inParamTypes[0]) {
+        return errors.New("Decorated " + target.shortString() + " cannot be passed as " +
+            factory._inParamTypes[0].String())
+    }
+    if !factory._outParamTypes[0].AssignableTo(target.key.type_) {
+        return errors.New("Decorator of " + target.shortString() + " must return " +
+            target.key.type_.String() + ", not " + factory._outParamTypes[0].String())
+    }
+
+    // the first param is the decorated instance, it is not resolved as a dependency
+    allDependencies, paramTypes := factory.collectDependencies()
+    dependencies := map[uint16]*dependency{}
+    for index, dependency := range allDependencies {
+        if index == 0 {
+            continue
+        }
+        dependency.index = index - 1
+        dependency.paramIndex--
+        dependencies[index-1] = dependency
+    }
+    decorator := &beanDefinition{
+        key:          binder.buildBindKey(),
+        dependencies: dependencies,
+        paramTypes:   paramTypes[1:],
+        priority:     binder.priority,
+        factory:      factory,
+        module:       binder.module,
+    }
+
+    target.decorators = append(target.decorators, decorator)
+    sort.SliceStable(target.decorators, func(i, j int) bool {
+        return target.decorators[i].priority < target.decorators[j].priority
+    })
+    target.decorate = func(res *resolution, instance interface{}) (interface{}, error) {
+        return ctx.applyDecorators(res, target, instance)
+    }
+    ctx.logger.WithFields(log.Fields{
+        "decorated": target.shortString(),
+        "priority":  binder.priority,
+    }).Info("Decorator bound")
+    return nil
+}
+
+// Wraps the instance by all the decorators of the definition, from the innermost to the outermost
+func (ctx *contextImpl) applyDecorators(
+    res *resolution,
+    definition *beanDefinition,
+    instance interface{},
+) (interface{}, error) {
+    for _, decorator := range definition.decorators {
+        params, e := ctx.resolveParams(res, decorator)
+        if e != nil {
+            return nil, e
+        }
+        inner := reflect.New(decorator.factory._inParamTypes[0]).Elem()
+        if instance != nil {
+            inner.Set(reflect.ValueOf(instance))
+        }
+        instance, e = decorator.factory.call(append([]reflect.Value{inner}, params...))
+        if e != nil {
+            return nil, &FactoryError{Definition: "decorator of " + definition.shortString(), Err: e}
+        }
+    }
+    return instance, nil
+}
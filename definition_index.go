@@ -0,0 +1,111 @@
+package pp_ioc
+
+import (
+    "reflect"
+    "sync"
+)
+
+// Indexes of the definitions by qualifier and by type, so finding the definitions
+// for a dependency does not scan all of them. Must be rebuilt when definitions change.
+type definitionIndex struct {
+    definitions []*beanDefinition
+    byQualifier map[string][]*beanDefinition
+    byMarker    map[reflect.Type][]*beanDefinition
+    // Definitions by the exact type of the key
+    byKeyType map[reflect.Type][]*beanDefinition
+    // Struct definitions by the struct type, both T and *T are indexed as T
+    byStruct map[reflect.Type][]*beanDefinition
+    // Definitions suitable for the other dependency types, e.g. interfaces,
+    // filled on the first request
+    byTypeCache map[reflect.Type][]*beanDefinition
+    // Guards the cache, which is filled by concurrent lookups too
+    cacheMutex sync.Mutex
+}
+
+func newDefinitionIndex(beanDefinitions *beanDefinitionContainer) *definitionIndex {
+    index := &definitionIndex{
+        definitions: beanDefinitions.all(),
+        byQualifier: map[string][]*beanDefinition{},
+        byMarker:    map[reflect.Type][]*beanDefinition{},
+        byKeyType:   map[reflect.Type][]*beanDefinition{},
+        byStruct:    map[reflect.Type][]*beanDefinition{},
+        byTypeCache: map[reflect.Type][]*beanDefinition{},
+    }
+    for _, definition := range index.definitions {
+        for _, qualifier := range definition.key.qualifiers {
+            index.byQualifier[qualifier] = append(index.byQualifier[qualifier], definition)
+        }
+        for _, alias := range definition.aliases {
+            index.byQualifier[alias] = append(index.byQualifier[alias], definition)
+        }
+        for _, marker := range definition.key.markers {
+            index.byMarker[marker] = append(index.byMarker[marker], definition)
+        }
+        index.byKeyType[definition.key.type_] = append(index.byKeyType[definition.key.type_], definition)
+        if structType, ok := structTypeOf(definition.key.type_); ok {
+            index.byStruct[structType] = append(index.byStruct[structType], definition)
+        }
+    }
+    return index
+}
+
+// Returns T for T and *T, where T is a struct
+func structTypeOf(type_ reflect.Type) (reflect.Type, bool) {
+    if type_.Kind() == reflect.Ptr {
+        type_ = type_.Elem()
+    }
+    return type_, type_.Kind() == reflect.Struct
+}
+
+// Returns the definitions suitable for the dependency, in the order of their priorities
+func (index *definitionIndex) find(dependency *dependency) []*beanDefinition {
+    if !dependency.isQualified() {
+        return index.findByType(dependency.beanType())
+    }
+    // exact qualifiers and markers narrow the candidates the most,
+    // only patterns need the scan by type
+    candidates, narrowed := index.findByExactQualifier(dependency)
+    if !narrowed && len(dependency.markers) > 0 {
+        candidates, narrowed = index.byMarker[dependency.markers[0]], true
+    }
+    if !narrowed {
+        candidates = index.findByType(dependency.beanType())
+    }
+    var res []*beanDefinition
+    for _, definition := range candidates {
+        if definition.isSuitableForDependencyByQualifier(dependency) &&
+            definition.isSuitableForDependencyByType(dependency) {
+            res = append(res, definition)
+        }
+    }
+    return res
+}
+
+// Returns the definitions with the first qualifier of the dependency which is not a pattern
+func (index *definitionIndex) findByExactQualifier(dependency *dependency) ([]*beanDefinition, bool) {
+    for _, qualifier := range dependency.qualifiers {
+        if !isQualifierPattern(qualifier) {
+            return index.byQualifier[qualifier], true
+        }
+    }
+    return nil, false
+}
+
+func (index *definitionIndex) findByType(type_ reflect.Type) []*beanDefinition {
+    if structType, ok := structTypeOf(type_); ok {
+        return index.byStruct[structType]
+    }
+    index.cacheMutex.Lock()
+    defer index.cacheMutex.Unlock()
+    if res, ok := index.byTypeCache[type_]; ok {
+        return res
+    }
+    var res []*beanDefinition
+    for _, definition := range index.definitions {
+        if definition.isSuitableForType(type_) {
+            res = append(res, definition)
+        }
+    }
+    index.byTypeCache[type_] = res
+    return res
+}
@@ -4,8 +4,12 @@ import (
     "github.com/pkg/errors"
     "reflect"
     "strconv"
+    "strings"
 )
 
+// Separates the qualifiers of the dependency, all of them must match
+const QualifierSep = ","
+
 type valueProvider struct {
     qualifier    string
     defaultValue string
@@ -15,12 +19,20 @@ type valueProvider struct {
 type dependency struct {
     name          string
     qualifier     string
+    qualifiers    []string // parsed from qualifier, may contain glob patterns
     hasQualifier  bool
     valueProvider *valueProvider
     type_         reflect.Type
     index         uint16
+    paramIndex    uint16 // position of the factory param, fields share the index of their struct
     isBean        bool
     isValue       bool
+    isProvider    bool // resolved lazily, so it has no graph edge
+    isCollection  bool // slice or map of all the beans matching the qualifier pattern
+    markers       []reflect.Type
+    wrapperType   reflect.Type // Qualified wrapper, which the bean is put into
+    constraints   []*valueConstraint
+    description   string
 }
 
 func newBeanDependency(
@@ -30,16 +42,57 @@ func newBeanDependency(
     type_ reflect.Type,
     index uint16,
 ) *dependency {
+    isProvider := type_ == reflect.TypeOf((*Provider)(nil)).Elem()
+    var qualifiers []string
+    if hasQualifier {
+        for _, part := range strings.Split(qualifier, QualifierSep) {
+            qualifiers = append(qualifiers, strings.TrimSpace(part))
+        }
+    }
+    isCollection := hasQualifier && isQualifierPattern(qualifier) &&
+        (type_.Kind() == reflect.Slice ||
+            (type_.Kind() == reflect.Map && type_.Key().Kind() == reflect.String))
     return &dependency{
         name:          name,
         qualifier:     qualifier,
+        qualifiers:    qualifiers,
         hasQualifier:  hasQualifier,
         valueProvider: nil,
         type_:         type_,
         index:         index,
-        isBean:        true,
+        isBean:        !isProvider,
         isValue:       false,
+        isProvider:    isProvider,
+        isCollection:  isCollection,
+    }
+}
+
+// Checks whether the qualifier is a glob pattern, see path.Match
+func isQualifierPattern(qualifier string) bool {
+    return strings.ContainsAny(qualifier, "*?[")
+}
+
+// Returns the first qualifier pattern of the collection, which names its beans in a map
+func (d *dependency) collectionPattern() string {
+    for _, qualifier := range d.qualifiers {
+        if isQualifierPattern(qualifier) {
+            return qualifier
+        }
     }
+    return ""
+}
+
+// Checks whether the dependency has string or marker qualifiers
+func (d *dependency) isQualified() bool {
+    return d.hasQualifier || len(d.markers) > 0
+}
+
+// Type of the bean, which is the element type for collections
+func (d *dependency) beanType() reflect.Type {
+    if d.isCollection {
+        return d.type_.Elem()
+    }
+    return d.type_
 }
 
 func newValueDependency(
@@ -66,88 +119,96 @@ func (d *dependency) String() string {
     return "Dep{" + d.qualifier + ":" + d.type_.String() + "}"
 }
 
+// The value is left out of the error, since it may be a decrypted secret
+func (d *dependency) conversionError(e error, typeName string) error {
+    if numError, ok := e.(*strconv.NumError); ok {
+        e = numError.Err
+    }
+    return errors.Wrap(e, "Property "+d.qualifier+" cannot be converted to "+typeName)
+}
+
 func (d *dependency) parsePropertyValue(propValue string) (reflect.Value, error) {
     switch d.type_.Kind() {
 
     case reflect.Bool:
         parsed, e := strconv.ParseBool(propValue)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to bool")
+            return reflect.Value{}, d.conversionError(e, "bool")
         }
         return reflect.ValueOf(parsed), nil
 
     case reflect.Int:
         parsed, e := strconv.ParseInt(propValue, 10, 64)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to int")
+            return reflect.Value{}, d.conversionError(e, "int")
         }
         return reflect.ValueOf(int(parsed)), nil
     case reflect.Int8:
         parsed, e := strconv.ParseInt(propValue, 10, 8)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to int8")
+            return reflect.Value{}, d.conversionError(e, "int8")
         }
         return reflect.ValueOf(int8(parsed)), nil
     case reflect.Int16:
         parsed, e := strconv.ParseInt(propValue, 10, 16)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to int16")
+            return reflect.Value{}, d.conversionError(e, "int16")
         }
         return reflect.ValueOf(int16(parsed)), nil
     case reflect.Int32:
         parsed, e := strconv.ParseInt(propValue, 10, 32)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to int32")
+            return reflect.Value{}, d.conversionError(e, "int32")
         }
         return reflect.ValueOf(int32(parsed)), nil
     case reflect.Int64:
         parsed, e := strconv.ParseInt(propValue, 10, 64)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to int64")
+            return reflect.Value{}, d.conversionError(e, "int64")
         }
         return reflect.ValueOf(int64(parsed)), nil
 
     case reflect.Uint:
         parsed, e := strconv.ParseUint(propValue, 10, 64)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to uint")
+            return reflect.Value{}, d.conversionError(e, "uint")
         }
-        return reflect.ValueOf(parsed), nil
+        return reflect.ValueOf(uint(parsed)), nil
     case reflect.Uint8:
         parsed, e := strconv.ParseUint(propValue, 10, 8)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to uint8")
+            return reflect.Value{}, d.conversionError(e, "uint8")
         }
         return reflect.ValueOf(uint8(parsed)), nil
     case reflect.Uint16:
         parsed, e := strconv.ParseUint(propValue, 10, 16)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to uint16")
+            return reflect.Value{}, d.conversionError(e, "uint16")
         }
         return reflect.ValueOf(uint16(parsed)), nil
     case reflect.Uint32:
         parsed, e := strconv.ParseUint(propValue, 10, 32)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to uint32")
+            return reflect.Value{}, d.conversionError(e, "uint32")
         }
         return reflect.ValueOf(uint32(parsed)), nil
     case reflect.Uint64:
         parsed, e := strconv.ParseUint(propValue, 10, 64)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to uint64")
+            return reflect.Value{}, d.conversionError(e, "uint64")
         }
         return reflect.ValueOf(uint64(parsed)), nil
 
     case reflect.Float32:
         parsed, e := strconv.ParseFloat(propValue, 32)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to float32")
+            return reflect.Value{}, d.conversionError(e, "float32")
         }
         return reflect.ValueOf(float32(parsed)), nil
     case reflect.Float64:
         parsed, e := strconv.ParseFloat(propValue, 64)
         if e != nil {
-            return reflect.Value{}, errors.Wrap(e, "Property "+propValue+" cannot be converted to float64")
+            return reflect.Value{}, d.conversionError(e, "float64")
         }
         return reflect.ValueOf(float64(parsed)), nil
 
@@ -0,0 +1,7 @@
+package pp_ioc
+
+// Beans implementing this interface are disposed when the context is closed,
+// in the reverse order of their instantiation
+type Disposer interface {
+    Dispose() error
+}
@@ -5,49 +5,145 @@ import (
     log "github.com/sirupsen/logrus"
     logCtx "github.com/wlad031/pp-logging"
     ps "github.com/wlad031/pp-properties/property_source"
+    "sort"
+    "sync"
+    "time"
 )
 
 type Environment interface {
     addPropertySource(b *bean) error
+    addPropertyDecryptor(b *bean) error
+    // Returns the poll interval and whether the environment is watching the sources
+    watchingInterval() (time.Duration, bool)
 
+    // Returns the property value. Values written as ENC(...)
+    // are decrypted by the registered PropertyDecryptors.
     GetProperty(key string) (string, error)
     GetPropertyOrDefault(key string, defaultValue string) string
+    // Returns all the properties as they are written in the sources,
+    // encrypted values are not decrypted
     GetAllProperties() map[string]string
+
+    // Returns all the properties together with the sources they came from
+    GetAllPropertyEntries() []PropertyEntry
+
+    // Subscribes the listener to the PropertiesChanged events
+    Subscribe(listener PropertiesListener)
+
+    // Starts watching the property sources for changes. Watchable sources
+    // report changes by themselves, FileBacked ones are polled with the given interval.
+    StartWatching(pollInterval time.Duration) error
+    StopWatching()
+}
+
+// Property together with the name of the property source it came from
+type PropertyEntry struct {
+    Key    string
+    Value  string
+    Source string
+    // Whether the property source marked the key as sensitive
+    // or the value is encrypted
+    Sensitive bool
 }
 
 func newEnvironment() Environment {
     return &environmentImpl{
         logger:          logCtx.Get("IOC.Environment"),
         propertySources: []ps.PropertySource{},
+        decrypted:       map[string]string{},
     }
 }
 
 type environmentImpl struct {
     logger          logCtx.NamedLogger
-    propertySources []ps.PropertySource
+    mutex           sync.RWMutex
+    propertySources []ps.PropertySource // ordered by precedence, the last one wins
+    sourceNames     []string
+    sourcePriority  []int
+    decryptors      []PropertyDecryptor
+    decrypted       map[string]string
+    listeners       []PropertiesListener
+    watcher         *environmentWatcher
+    pollInterval    time.Duration
 }
 
+// Sources are ordered by their priorities, so the properties of the sources with lower
+// priorities override the ones with higher, whatever order the sources are instantiated in.
+// Sources with the same priority override each other in the order they are added.
 func (env *environmentImpl) addPropertySource(b *bean) error {
     propertySource := b.instance.(ps.PropertySource)
-    env.propertySources = append(env.propertySources, propertySource)
+    priority := b.definition.priority
+    env.mutex.Lock()
+    i := sort.Search(len(env.sourcePriority), func(i int) bool {
+        return env.sourcePriority[i] < priority
+    })
+    env.propertySources = append(env.propertySources, nil)
+    copy(env.propertySources[i+1:], env.propertySources[i:])
+    env.propertySources[i] = propertySource
+    env.sourceNames = append(env.sourceNames, "")
+    copy(env.sourceNames[i+1:], env.sourceNames[i:])
+    env.sourceNames[i] = b.definition.shortString()
+    env.sourcePriority = append(env.sourcePriority, 0)
+    copy(env.sourcePriority[i+1:], env.sourcePriority[i:])
+    env.sourcePriority[i] = priority
+    env.mutex.Unlock()
     env.logger.WithFields(log.Fields{
         "beanDef": b.definition.shortString(),
     }).Info("Property source added")
     return nil
 }
 
+func (env *environmentImpl) addPropertyDecryptor(b *bean) error {
+    decryptor := b.instance.(PropertyDecryptor)
+    env.mutex.Lock()
+    env.decryptors = append(env.decryptors, decryptor)
+    env.mutex.Unlock()
+    env.logger.WithFields(log.Fields{
+        "beanDef": b.definition.shortString(),
+    }).Info("Property decryptor added")
+    return nil
+}
+
 func (env *environmentImpl) GetProperty(key string) (string, error) {
+    env.mutex.RLock()
     var res string
     for _, propertySource := range env.propertySources {
         if v, e := propertySource.Get(key); e == nil {
             res = v
         }
     }
+    env.mutex.RUnlock()
     if res == "" {
         return "", errors.New("Cannot find property " + key)
-    } else {
-        return res, nil
     }
+    if isEncryptedValue(res) {
+        return env.decrypt(key, res)
+    }
+    return res, nil
+}
+
+func (env *environmentImpl) decrypt(key string, value string) (string, error) {
+    env.mutex.RLock()
+    plainText, ok := env.decrypted[value]
+    decryptors := env.decryptors
+    env.mutex.RUnlock()
+    if ok {
+        return plainText, nil
+    }
+    if len(decryptors) == 0 {
+        return "", errors.New("Property " + key + " is encrypted, but there is no PropertyDecryptor")
+    }
+    var lastError error
+    for _, decryptor := range decryptors {
+        plainText, lastError = decryptor.Decrypt(unwrapEncryptedValue(value))
+        if lastError == nil {
+            env.mutex.Lock()
+            env.decrypted[value] = plainText
+            env.mutex.Unlock()
+            return plainText, nil
+        }
+    }
+    return "", errors.Wrap(lastError, "Cannot decrypt property "+key)
 }
 
 func (env *environmentImpl) GetPropertyOrDefault(key string, defaultValue string) string {
@@ -59,6 +155,8 @@ func (env *environmentImpl) GetPropertyOrDefault(key string, defaultValue string
 }
 
 func (env *environmentImpl) GetAllProperties() map[string]string {
+    env.mutex.RLock()
+    defer env.mutex.RUnlock()
     res := map[string]string{}
     for _, propertySource := range env.propertySources {
         allProps := propertySource.GetAll()
@@ -68,3 +166,96 @@ func (env *environmentImpl) GetAllProperties() map[string]string {
     }
     return res
 }
+
+func (env *environmentImpl) GetAllPropertyEntries() []PropertyEntry {
+    env.mutex.RLock()
+    defer env.mutex.RUnlock()
+    entries := map[string]PropertyEntry{}
+    var keys []string
+    for i, propertySource := range env.propertySources {
+        sensitiveSource, isSensitiveSource := propertySource.(SensitivePropertySource)
+        for k, v := range propertySource.GetAll() {
+            if _, ok := entries[k]; !ok {
+                keys = append(keys, k)
+            }
+            entries[k] = PropertyEntry{
+                Key:       k,
+                Value:     v,
+                Source:    env.sourceNames[i],
+                Sensitive: isEncryptedValue(v) || (isSensitiveSource && sensitiveSource.IsSensitive(k)),
+            }
+        }
+    }
+    res := make([]PropertyEntry, 0, len(keys))
+    for _, k := range keys {
+        res = append(res, entries[k])
+    }
+    return res
+}
+
+func (env *environmentImpl) Subscribe(listener PropertiesListener) {
+    env.mutex.Lock()
+    defer env.mutex.Unlock()
+    env.listeners = append(env.listeners, listener)
+}
+
+func (env *environmentImpl) StartWatching(pollInterval time.Duration) error {
+    env.mutex.Lock()
+    if env.watcher != nil {
+        env.mutex.Unlock()
+        return errors.New("Environment is already watching property sources")
+    }
+    sources := make([]ps.PropertySource, len(env.propertySources))
+    copy(sources, env.propertySources)
+    watcher := newEnvironmentWatcher(sources, env.publish)
+    env.watcher = watcher
+    env.pollInterval = pollInterval
+    env.mutex.Unlock()
+
+    // watchable sources may publish synchronously, which needs the lock
+    if e := watcher.start(pollInterval); e != nil {
+        env.mutex.Lock()
+        owned := env.watcher == watcher
+        if owned {
+            env.watcher = nil
+        }
+        env.mutex.Unlock()
+        if owned { // otherwise StopWatching has already closed it
+            watcher.close()
+        }
+        return errors.Wrap(e, "Cannot start watching property sources")
+    }
+    env.logger.Info("Watching property sources for changes")
+    return nil
+}
+
+func (env *environmentImpl) watchingInterval() (time.Duration, bool) {
+    env.mutex.RLock()
+    defer env.mutex.RUnlock()
+    return env.pollInterval, env.watcher != nil
+}
+
+func (env *environmentImpl) StopWatching() {
+    env.mutex.Lock()
+    watcher := env.watcher
+    env.watcher = nil
+    env.mutex.Unlock()
+    if watcher != nil {
+        watcher.close()
+    }
+}
+
+func (env *environmentImpl) publish(keys []string) {
+    env.mutex.RLock()
+    listeners := make([]PropertiesListener, len(env.listeners))
+    copy(listeners, env.listeners)
+    env.mutex.RUnlock()
+
+    env.logger.WithFields(log.Fields{
+        "keys": keys,
+    }).Info("Properties changed")
+    event := PropertiesChanged{Keys: keys}
+    for _, listener := range listeners {
+        listener(event)
+    }
+}
@@ -3,27 +3,86 @@ package pp_ioc
 import (
     log "github.com/sirupsen/logrus"
     logCtx "github.com/wlad031/pp-logging"
+    "sort"
+    "strings"
 )
 
 type EnvironmentPrinter interface {
     PostProcessor
+
+    // Adds key patterns of the properties which values must be masked
+    MaskKeys(patterns ...string) EnvironmentPrinter
+    // Enables or disables detection of secrets by the property value itself
+    DetectSecretValues(detect bool) EnvironmentPrinter
+    // Prints the properties sorted by key
+    Sorted() EnvironmentPrinter
+    // Prints only the properties which keys start with the given prefix
+    WithPrefix(prefix string) EnvironmentPrinter
+    // Prints the property source of every property
+    WithSources() EnvironmentPrinter
 }
 
 func NewEnvironmentPrinter() EnvironmentPrinter {
-    return &environmentPrinterImpl{logger: logCtx.Get("EnvironmentPrinter")}
+    return &environmentPrinterImpl{
+        logger:   logCtx.Get("EnvironmentPrinter"),
+        redactor: NewRedactor(),
+    }
 }
 
 type environmentPrinterImpl struct {
-    logger logCtx.NamedLogger
+    logger      logCtx.NamedLogger
+    redactor    *Redactor
+    sorted      bool
+    prefix      string
+    withSources bool
+}
+
+func (ep *environmentPrinterImpl) MaskKeys(patterns ...string) EnvironmentPrinter {
+    ep.redactor.KeyPatterns(patterns...)
+    return ep
+}
+
+func (ep *environmentPrinterImpl) DetectSecretValues(detect bool) EnvironmentPrinter {
+    ep.redactor.DetectValues(detect)
+    return ep
+}
+
+func (ep *environmentPrinterImpl) Sorted() EnvironmentPrinter {
+    ep.sorted = true
+    return ep
+}
+
+func (ep *environmentPrinterImpl) WithPrefix(prefix string) EnvironmentPrinter {
+    ep.prefix = prefix
+    return ep
+}
+
+func (ep *environmentPrinterImpl) WithSources() EnvironmentPrinter {
+    ep.withSources = true
+    return ep
 }
 
 func (ep *environmentPrinterImpl) PostProcess(ctx Context) error {
-    properties := ctx.GetEnvironment().GetAllProperties()
-    for key, value := range properties {
-        ep.logger.WithFields(log.Fields{
-            "key": key,
-            "value": value,
-        }).Info("Found property")
+    var entries []PropertyEntry
+    for _, entry := range ctx.GetEnvironment().GetAllPropertyEntries() {
+        if strings.HasPrefix(entry.Key, ep.prefix) {
+            entries = append(entries, entry)
+        }
+    }
+    if ep.sorted {
+        sort.Slice(entries, func(i, j int) bool {
+            return entries[i].Key < entries[j].Key
+        })
+    }
+    for _, entry := range entries {
+        fields := log.Fields{
+            "key":   entry.Key,
+            "value": ep.redactor.Redact(entry),
+        }
+        if ep.withSources {
+            fields["source"] = entry.Source
+        }
+        ep.logger.WithFields(fields).Info("Found property")
     }
     return nil
 }
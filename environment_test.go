@@ -0,0 +1,45 @@
+package pp_ioc
+
+import (
+    "reflect"
+    "testing"
+)
+
+func newPropertySourceBean(priority int, properties map[string]string) *bean {
+    source := &testPropertySource{properties: properties}
+    return &bean{
+        definition: &beanDefinition{
+            key: &bindKey{
+                qualifiers: []string{"properties"},
+                type_:      reflect.TypeOf(source),
+            },
+            priority: priority,
+        },
+        instance: source,
+    }
+}
+
+func TestLowerPriorityPropertySourceWins(t *testing.T) {
+    env := newEnvironment()
+    // added in the reverse order of their priorities, as the dependencies may require
+    sources := []*bean{
+        newPropertySourceBean(PropertySourceLowestPriority, map[string]string{"db.url": "mock"}),
+        newPropertySourceBean(PropertySourceHighestPriority, map[string]string{"db.url": "file", "app.name": "app"}),
+        newPropertySourceBean(PropertySourceHighestPriority, map[string]string{"app.name": "other"}),
+    }
+    for _, source := range sources {
+        if e := env.addPropertySource(source); e != nil {
+            t.Fatal(e)
+        }
+    }
+
+    if url, _ := env.GetProperty("db.url"); url != "mock" {
+        t.Errorf("Expected the property of the source with the lowest priority, got %s", url)
+    }
+    if name, _ := env.GetProperty("app.name"); name != "other" {
+        t.Errorf("Expected the property of the source added last among the equal ones, got %s", name)
+    }
+    if url := env.GetAllProperties()["db.url"]; url != "mock" {
+        t.Errorf("Expected all the properties to be overridden the same way, got %s", url)
+    }
+}
@@ -0,0 +1,189 @@
+package pp_ioc
+
+import (
+    log "github.com/sirupsen/logrus"
+    logCtx "github.com/wlad031/pp-logging"
+    ps "github.com/wlad031/pp-properties/property_source"
+    "os"
+    "sort"
+    "sync"
+    "time"
+)
+
+// Event published by the Environment when some properties have been changed
+type PropertiesChanged struct {
+    Keys []string
+}
+
+type PropertiesListener func(event PropertiesChanged)
+
+// Property source that is able to report its changes by itself.
+// The source must call onChange with the keys of the changed properties.
+type Watchable interface {
+    Watch(onChange func(keys []string)) error
+    Unwatch() error
+}
+
+// Property source that is backed by a file.
+// Such sources are polled for the file modification time and reloaded on change.
+type FileBacked interface {
+    FilePath() string
+    Reload() error
+}
+
+type environmentWatcher struct {
+    logger    logCtx.NamedLogger
+    publish   func(keys []string)
+    sources   []ps.PropertySource
+    modTimes  map[ps.PropertySource]time.Time
+    snapshots map[ps.PropertySource]map[string]string
+    stop      chan struct{}
+    wg        sync.WaitGroup
+
+    // Sources which have been watched successfully, only these are unwatched on close
+    watched      []Watchable
+    closed       bool
+    watchedMutex sync.Mutex
+}
+
+func newEnvironmentWatcher(sources []ps.PropertySource, publish func(keys []string)) *environmentWatcher {
+    return &environmentWatcher{
+        logger:    logCtx.Get("IOC.EnvironmentWatcher"),
+        publish:   publish,
+        sources:   sources,
+        modTimes:  map[ps.PropertySource]time.Time{},
+        snapshots: map[ps.PropertySource]map[string]string{},
+        stop:      make(chan struct{}),
+    }
+}
+
+func (w *environmentWatcher) start(pollInterval time.Duration) error {
+    var polled []ps.PropertySource
+    for _, source := range w.sources {
+        if watchable, ok := source.(Watchable); ok {
+            if e := watchable.Watch(w.publish); e != nil {
+                return e
+            }
+            w.addWatched(watchable)
+            continue
+        }
+        if fileBacked, ok := source.(FileBacked); ok {
+            if info, e := os.Stat(fileBacked.FilePath()); e == nil {
+                w.modTimes[source] = info.ModTime()
+            }
+            w.snapshots[source] = copyProperties(source.GetAll())
+            polled = append(polled, source)
+        }
+    }
+    if len(polled) == 0 {
+        return nil
+    }
+    w.wg.Add(1)
+    go w.poll(polled, pollInterval)
+    return nil
+}
+
+// Remembers the watched source or unwatches it at once, if the watcher has been closed meanwhile
+func (w *environmentWatcher) addWatched(watchable Watchable) {
+    w.watchedMutex.Lock()
+    if !w.closed {
+        w.watched = append(w.watched, watchable)
+        w.watchedMutex.Unlock()
+        return
+    }
+    w.watchedMutex.Unlock()
+    w.unwatch(watchable)
+}
+
+func (w *environmentWatcher) unwatch(watchable Watchable) {
+    if e := watchable.Unwatch(); e != nil {
+        w.logger.WithFields(log.Fields{
+            "error": e.Error(),
+        }).Warn("Cannot stop watching property source")
+    }
+}
+
+func (w *environmentWatcher) poll(sources []ps.PropertySource, pollInterval time.Duration) {
+    defer w.wg.Done()
+    ticker := time.NewTicker(pollInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-w.stop:
+            return
+        case <-ticker.C:
+            var changed []string
+            for _, source := range sources {
+                changed = append(changed, w.checkFile(source)...)
+            }
+            if len(changed) > 0 {
+                w.publish(changed)
+            }
+        }
+    }
+}
+
+func (w *environmentWatcher) checkFile(source ps.PropertySource) []string {
+    fileBacked := source.(FileBacked)
+    info, e := os.Stat(fileBacked.FilePath())
+    if e != nil {
+        w.logger.WithFields(log.Fields{
+            "file":  fileBacked.FilePath(),
+            "error": e.Error(),
+        }).Warn("Cannot stat property file")
+        return nil
+    }
+    if info.ModTime().Equal(w.modTimes[source]) {
+        return nil
+    }
+    w.modTimes[source] = info.ModTime()
+    if e := fileBacked.Reload(); e != nil {
+        w.logger.WithFields(log.Fields{
+            "file":  fileBacked.FilePath(),
+            "error": e.Error(),
+        }).Warn("Cannot reload property file")
+        return nil
+    }
+    current := copyProperties(source.GetAll())
+    changed := diffProperties(w.snapshots[source], current)
+    w.snapshots[source] = current
+    return changed
+}
+
+func (w *environmentWatcher) close() {
+    close(w.stop)
+    w.wg.Wait()
+    w.watchedMutex.Lock()
+    watched := w.watched
+    w.watched = nil
+    w.closed = true
+    w.watchedMutex.Unlock()
+    for _, watchable := range watched {
+        w.unwatch(watchable)
+    }
+}
+
+func copyProperties(properties map[string]string) map[string]string {
+    res := make(map[string]string, len(properties))
+    for k, v := range properties {
+        res[k] = v
+    }
+    return res
+}
+
+// Returns sorted keys which were added, removed or changed
+func diffProperties(old map[string]string, new map[string]string) []string {
+    var keys []string
+    for k, v := range new {
+        if oldValue, ok := old[k]; !ok || oldValue != v {
+            keys = append(keys, k)
+        }
+    }
+    for k := range old {
+        if _, ok := new[k]; !ok {
+            keys = append(keys, k)
+        }
+    }
+    sort.Strings(keys)
+    return keys
+}
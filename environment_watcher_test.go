@@ -0,0 +1,154 @@
+package pp_ioc
+
+import (
+    "errors"
+    "sync"
+    "testing"
+    "time"
+
+    ps "github.com/wlad031/pp-properties/property_source"
+)
+
+// Watchable source counting the Watch and Unwatch calls
+type watchableSource struct {
+    testPropertySource
+    watchMutex sync.Mutex
+    watches    int
+    unwatches  int
+    onChange   func(keys []string)
+    watchError error
+}
+
+func newWatchableSource(properties map[string]string) *watchableSource {
+    return &watchableSource{testPropertySource: testPropertySource{properties: properties}}
+}
+
+func (s *watchableSource) Watch(onChange func(keys []string)) error {
+    s.watchMutex.Lock()
+    defer s.watchMutex.Unlock()
+    if s.watchError != nil {
+        return s.watchError
+    }
+    s.watches++
+    s.onChange = onChange
+    return nil
+}
+
+func (s *watchableSource) Unwatch() error {
+    s.watchMutex.Lock()
+    defer s.watchMutex.Unlock()
+    s.unwatches++
+    s.onChange = nil
+    return nil
+}
+
+func (s *watchableSource) counts() (int, int) {
+    s.watchMutex.Lock()
+    defer s.watchMutex.Unlock()
+    return s.watches, s.unwatches
+}
+
+// Sets the property and reports the change, if the source is watched
+func (s *watchableSource) change(key string, value string) {
+    s.set(key, value)
+    s.watchMutex.Lock()
+    onChange := s.onChange
+    s.watchMutex.Unlock()
+    if onChange != nil {
+        onChange([]string{key})
+    }
+}
+
+type watchableConfiguration struct {
+    source *watchableSource
+}
+
+func (c *watchableConfiguration) Bind(ctx Context) error {
+    ctx.NewPropertySourceBinder().
+        Qualifiers("watchableProperties").
+        Factory(func() *watchableSource {
+            return c.source
+        })
+    ctx.NewBinder().
+        Qualifiers("repository").
+        Scope(ScopeRefresh).
+        Factory(func(p struct {
+            Url string `value:"${db.url}"`
+        }) *testRepository {
+            return &testRepository{url: p.Url}
+        })
+    return nil
+}
+
+func newWatchableContext(t *testing.T, source *watchableSource) Context {
+    t.Helper()
+    ctx := NewContext()
+    if e := ctx.Register(&watchableConfiguration{source: source}); e != nil {
+        t.Fatal(e)
+    }
+    if e := ctx.Build(); e != nil {
+        t.Fatal(e)
+    }
+    t.Cleanup(func() {
+        if e := ctx.Close(); e != nil {
+            t.Error(e)
+        }
+    })
+    return ctx
+}
+
+func TestRefreshRestartsWatching(t *testing.T) {
+    source := newWatchableSource(map[string]string{"db.url": "url-0"})
+    ctx := newWatchableContext(t, source)
+    if e := ctx.GetEnvironment().StartWatching(time.Second); e != nil {
+        t.Fatal(e)
+    }
+    if e := ctx.Refresh(); e != nil {
+        t.Fatal(e)
+    }
+    if watches, unwatches := source.counts(); watches != 2 || unwatches != 1 {
+        t.Fatalf("Expected the source to be watched again, got %d watches and %d unwatches",
+            watches, unwatches)
+    }
+
+    source.change("db.url", "url-1")
+    repository, e := ctx.GetBeanByName("repository")
+    if e != nil {
+        t.Fatal(e)
+    }
+    if url := repository.(*testRepository).url; url != "url-1" {
+        t.Errorf("Expected the repository rebuilt after the refresh with url-1, got %s", url)
+    }
+}
+
+func TestRefreshDoesNotStartWatching(t *testing.T) {
+    source := newWatchableSource(map[string]string{"db.url": "url-0"})
+    ctx := newWatchableContext(t, source)
+    if e := ctx.Refresh(); e != nil {
+        t.Fatal(e)
+    }
+    if watches, _ := source.counts(); watches != 0 {
+        t.Errorf("Expected the source not to be watched, got %d watches", watches)
+    }
+}
+
+func TestFailedStartUnwatchesOnlyWatchedSources(t *testing.T) {
+    watched := newWatchableSource(map[string]string{})
+    failing := newWatchableSource(map[string]string{})
+    failing.watchError = errors.New("Cannot connect")
+    skipped := newWatchableSource(map[string]string{})
+    watcher := newEnvironmentWatcher([]ps.PropertySource{watched, failing, skipped}, func(keys []string) {})
+
+    if e := watcher.start(time.Second); e == nil {
+        t.Fatal("Expected the error of the failing source")
+    }
+    watcher.close()
+    if _, unwatches := watched.counts(); unwatches != 1 {
+        t.Errorf("Expected the watched source to be unwatched, got %d unwatches", unwatches)
+    }
+    for _, source := range []*watchableSource{failing, skipped} {
+        if _, unwatches := source.counts(); unwatches != 0 {
+            t.Errorf("Expected the source which was not watched to stay untouched, got %d unwatches", unwatches)
+        }
+    }
+}
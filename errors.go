@@ -0,0 +1,137 @@
+package pp_ioc
+
+import (
+    "strconv"
+    "strings"
+)
+
+// Error which aggregates several independent errors
+type MultiError struct {
+    Errors []error
+}
+
+func (me *MultiError) Error() string {
+    if len(me.Errors) == 1 {
+        return me.Errors[0].Error()
+    }
+    messages := make([]string, 0, len(me.Errors))
+    for _, e := range me.Errors {
+        messages = append(messages, "\t* "+e.Error())
+    }
+    return strconv.Itoa(len(me.Errors)) + " errors occurred:\n" + strings.Join(messages, "\n")
+}
+
+func (me *MultiError) append(e error) {
+    if e == nil {
+        return
+    }
+    if nested, ok := e.(*MultiError); ok {
+        me.Errors = append(me.Errors, nested.Errors...)
+        return
+    }
+    me.Errors = append(me.Errors, e)
+}
+
+func (me *MultiError) errorOrNil() error {
+    if len(me.Errors) == 0 {
+        return nil
+    }
+    return me
+}
+
+// Error describing a value which violates the constraints of the injecting field
+type ValidationError struct {
+    Bean       string
+    Field      string
+    Property   string
+    Constraint string
+    Message    string
+}
+
+func (ve *ValidationError) Error() string {
+    return "Invalid value of property " + ve.Property +
+        " for field " + ve.Field + " of " + ve.Bean + ": " + ve.Message
+}
+
+// Error returned when no bean is suitable for the dependency
+type MissingBeanError struct {
+    // Definition which requested the dependency
+    Definition string
+    Dependency string
+    // Definitions which are suitable by type but not by qualifier
+    Candidates []string
+    // Similar qualifier or type, if any
+    Suggestion string
+}
+
+func (me *MissingBeanError) Error() string {
+    message := "Cannot find bean for dependency " + me.Dependency
+    if me.Definition != "" {
+        message += " of " + me.Definition
+    }
+    if len(me.Candidates) > 0 {
+        message += "; suitable by type: [" + strings.Join(me.Candidates, ",") + "]"
+    }
+    if me.Suggestion != "" {
+        message += "; did you mean " + me.Suggestion + "?"
+    }
+    return message
+}
+
+// Error returned when two or more beans are suitable for the dependency
+type AmbiguousBeanError struct {
+    // Definition which requested the dependency
+    Definition string
+    Dependency string
+    Candidates []string
+}
+
+func (ae *AmbiguousBeanError) Error() string {
+    message := "Two or more beans are suitable for dependency " + ae.Dependency
+    if ae.Definition != "" {
+        message += " of " + ae.Definition
+    }
+    return message + ": [" + strings.Join(ae.Candidates, ",") + "]"
+}
+
+// Error returned by the bean factory
+type FactoryError struct {
+    Definition string
+    Err        error
+}
+
+func (fe *FactoryError) Error() string {
+    return "Error happened during calling bean factory of " + fe.Definition + ": " + fe.Err.Error()
+}
+
+func (fe *FactoryError) Cause() error {
+    return fe.Err
+}
+
+func (fe *FactoryError) Unwrap() error {
+    return fe.Err
+}
+
+// Error returned when the property cannot be found or converted to the field type
+type PropertyError struct {
+    Definition string
+    Field      string
+    Property   string
+    Err        error
+}
+
+func (pe *PropertyError) Error() string {
+    message := "Cannot inject property " + pe.Property
+    if pe.Definition != "" {
+        message += " into field " + pe.Field + " of " + pe.Definition
+    }
+    return message + ": " + pe.Err.Error()
+}
+
+func (pe *PropertyError) Cause() error {
+    return pe.Err
+}
+
+func (pe *PropertyError) Unwrap() error {
+    return pe.Err
+}
@@ -0,0 +1,260 @@
+package pp_ioc
+
+import (
+    "encoding/json"
+    "github.com/pkg/errors"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+type GraphFormat string
+
+const (
+    GraphFormatDot     GraphFormat = "dot"
+    GraphFormatMermaid GraphFormat = "mermaid"
+    GraphFormatJson    GraphFormat = "json"
+)
+
+const (
+    nodeKindBean              = "bean"
+    nodeKindInfrastructure    = "infrastructure"
+    nodeKindPropertySource    = "propertySource"
+    nodeKindPropertyDecryptor = "propertyDecryptor"
+    nodeKindPostProcessor     = "postProcessor"
+)
+
+type graphNodeExport struct {
+    Id         string   `json:"id"`
+    Qualifiers []string `json:"qualifiers"`
+    Markers    []string `json:"markers,omitempty"`
+    Type       string   `json:"type"`
+    Scope      string   `json:"scope"`
+    Priority   int      `json:"priority"`
+    Kind       string   `json:"kind"`
+    Module     string   `json:"module,omitempty"`
+}
+
+type graphEdgeExport struct {
+    From  string `json:"from"`
+    To    string `json:"to"`
+    Label string `json:"label"`
+}
+
+type graphExport struct {
+    Nodes []*graphNodeExport `json:"nodes"`
+    Edges []*graphEdgeExport `json:"edges"`
+}
+
+func (ctx *contextImpl) ExportGraph(format GraphFormat) (string, error) {
+    ctx.buildMutex.Lock()
+    defer ctx.buildMutex.Unlock()
+    if e := ctx.buildGraph(); e != nil {
+        return "", e
+    }
+    export := newGraphExport(ctx.graph)
+    switch format {
+    case GraphFormatDot:
+        return export.dot(), nil
+    case GraphFormatMermaid:
+        return export.mermaid(), nil
+    case GraphFormatJson:
+        res, e := json.MarshalIndent(export, "", "  ")
+        if e != nil {
+            return "", errors.Wrap(e, "Cannot export the graph to JSON")
+        }
+        return string(res), nil
+    }
+    return "", errors.New("Unknown graph format " + string(format))
+}
+
+func newGraphExport(ctxG *contextGraph) *graphExport {
+    export := &graphExport{
+        Nodes: []*graphNodeExport{},
+        Edges: []*graphEdgeExport{},
+    }
+    for _, definition := range ctxG.definitions {
+        qualifiers := definition.key.qualifiers
+        if qualifiers == nil {
+            qualifiers = []string{}
+        }
+        var markers []string
+        for _, marker := range definition.key.markers {
+            markers = append(markers, marker.String())
+        }
+        export.Nodes = append(export.Nodes, &graphNodeExport{
+            Id:         graphNodeId(definition.graphIndex),
+            Qualifiers: qualifiers,
+            Markers:    markers,
+            Type:       definition.key.type_.String(),
+            Scope:      definition.scopeString(),
+            Priority:   definition.priority,
+            Kind:       graphNodeKind(definition),
+            Module:     definition.module,
+        })
+    }
+    for _, edge := range ctxG.edges {
+        export.Edges = append(export.Edges, &graphEdgeExport{
+            From:  graphNodeId(edge.from),
+            To:    graphNodeId(edge.to),
+            Label: graphEdgeLabel(edge.dependency),
+        })
+    }
+    return export
+}
+
+func graphNodeId(index int) string {
+    return "n" + strconv.Itoa(index)
+}
+
+func graphNodeKind(definition *beanDefinition) string {
+    switch {
+    case definition.key.type_ == reflect.TypeOf((*Context)(nil)).Elem(),
+        definition.key.type_ == reflect.TypeOf((*Environment)(nil)).Elem():
+        return nodeKindInfrastructure
+    case definition.isPropertySource():
+        return nodeKindPropertySource
+    case definition.isPropertyDecryptor():
+        return nodeKindPropertyDecryptor
+    case definition.isPostProcessor():
+        return nodeKindPostProcessor
+    }
+    return nodeKindBean
+}
+
+// Struct field name or the index of the factory parameter
+func graphEdgeLabel(dependency *dependency) string {
+    if dependency.name != "" {
+        return dependency.name
+    }
+    return "#" + strconv.Itoa(int(dependency.paramIndex))
+}
+
+func (node *graphNodeExport) labelLines() []string {
+    qualifiers := "[" + strings.Join(node.Qualifiers, ",") + "]"
+    if len(node.Markers) > 0 {
+        qualifiers += "<" + strings.Join(node.Markers, ",") + ">"
+    }
+    return []string{
+        qualifiers,
+        node.Type,
+        node.Scope + ", priority " + strconv.Itoa(node.Priority),
+    }
+}
+
+var dotNodeStyles = map[string]string{
+    nodeKindBean:              `shape=box`,
+    nodeKindInfrastructure:    `shape=box, style="filled,dashed", fillcolor=lightgrey`,
+    nodeKindPropertySource:    `shape=cylinder, style=filled, fillcolor=lightyellow`,
+    nodeKindPropertyDecryptor: `shape=cylinder, style=filled, fillcolor=mistyrose`,
+    nodeKindPostProcessor:     `shape=component, style=filled, fillcolor=lightblue`,
+}
+
+// Returns the module names in order of their first appearance, "" stands for no module
+func (export *graphExport) modules() []string {
+    var modules []string
+    seen := map[string]bool{}
+    for _, node := range export.Nodes {
+        if !seen[node.Module] {
+            seen[node.Module] = true
+            modules = append(modules, node.Module)
+        }
+    }
+    return modules
+}
+
+func (export *graphExport) dot() string {
+    var sb strings.Builder
+    sb.WriteString("digraph Context {\n")
+    sb.WriteString("    rankdir=LR;\n")
+    for i, module := range export.modules() {
+        indent := "    "
+        if module != "" {
+            sb.WriteString("    subgraph cluster_" + strconv.Itoa(i) + " {\n")
+            sb.WriteString(`        label="` + dotEscape(module) + `";` + "\n")
+            indent = "        "
+        }
+        for _, node := range export.Nodes {
+            if node.Module != module {
+                continue
+            }
+            lines := node.labelLines()
+            for j, line := range lines {
+                lines[j] = dotEscape(line)
+            }
+            sb.WriteString(indent + node.Id + ` [label="` + strings.Join(lines, `\n`) + `", ` +
+                dotNodeStyles[node.Kind] + "];\n")
+        }
+        if module != "" {
+            sb.WriteString("    }\n")
+        }
+    }
+    for _, edge := range export.Edges {
+        sb.WriteString("    " + edge.From + " -> " + edge.To +
+            ` [label="` + dotEscape(edge.Label) + `"];` + "\n")
+    }
+    sb.WriteString("}\n")
+    return sb.String()
+}
+
+var mermaidClassStyles = map[string]string{
+    nodeKindInfrastructure:    "fill:#eeeeee,stroke-dasharray:5 5",
+    nodeKindPropertySource:    "fill:#ffffe0",
+    nodeKindPropertyDecryptor: "fill:#ffe4e1",
+    nodeKindPostProcessor:     "fill:#add8e6",
+}
+
+func (export *graphExport) mermaid() string {
+    var sb strings.Builder
+    sb.WriteString("graph LR\n")
+    for i, module := range export.modules() {
+        indent := "    "
+        if module != "" {
+            sb.WriteString("    subgraph m" + strconv.Itoa(i) + `["` + mermaidEscape(module) + `"]` + "\n")
+            indent = "        "
+        }
+        for _, node := range export.Nodes {
+            if node.Module != module {
+                continue
+            }
+            lines := node.labelLines()
+            for j, line := range lines {
+                lines[j] = mermaidEscape(line)
+            }
+            sb.WriteString(indent + node.Id + `["` + strings.Join(lines, "<br/>") + `"]` + "\n")
+        }
+        if module != "" {
+            sb.WriteString("    end\n")
+        }
+    }
+    for _, edge := range export.Edges {
+        sb.WriteString("    " + edge.From + ` -->|"` + mermaidEscape(edge.Label) + `"| ` + edge.To + "\n")
+    }
+    for _, kind := range []string{
+        nodeKindInfrastructure,
+        nodeKindPropertySource,
+        nodeKindPropertyDecryptor,
+        nodeKindPostProcessor,
+    } {
+        var ids []string
+        for _, node := range export.Nodes {
+            if node.Kind == kind {
+                ids = append(ids, node.Id)
+            }
+        }
+        if len(ids) == 0 {
+            continue
+        }
+        sb.WriteString("    classDef " + kind + " " + mermaidClassStyles[kind] + "\n")
+        sb.WriteString("    class " + strings.Join(ids, ",") + " " + kind + "\n")
+    }
+    return sb.String()
+}
+
+func dotEscape(s string) string {
+    return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+func mermaidEscape(s string) string {
+    return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
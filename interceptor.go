@@ -0,0 +1,203 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+    log "github.com/sirupsen/logrus"
+    logCtx "github.com/wlad031/pp-logging"
+    "reflect"
+    "sort"
+    "sync"
+)
+
+// Call of the method of the intercepted bean
+type Invocation interface {
+    Target() interface{}
+    Method() string
+    Args() []interface{}
+    // Calls the next interceptor or the method itself. Returns all the results
+    // of the method, if the last one is a non-nil error, it is returned as the error too.
+    Proceed() ([]interface{}, error)
+}
+
+// Bean applied to the methods of other beans, see Binder.Intercepts.
+// The error returned for a method with the error result is returned by the method.
+// Methods without the error result cannot report it, so the proxy logs the error
+// and returns zero values instead.
+type MethodInterceptor interface {
+    Invoke(inv Invocation) ([]interface{}, error)
+}
+
+// Creates the proxy of the interface, which calls the target through the chain
+type ProxyFactory func(target interface{}, chain *InterceptorChain) interface{}
+
+var (
+    proxyFactoriesMutex sync.RWMutex
+    proxyFactories      = map[reflect.Type]ProxyFactory{}
+)
+
+// Registers the proxy factory for the interface given as a nil pointer, e.g. (*Service)(nil).
+// Called by the code generated by ioc-proxygen.
+func RegisterProxy(iface interface{}, factory ProxyFactory) {
+    type_ := reflect.TypeOf(iface).Elem()
+    proxyFactoriesMutex.Lock()
+    defer proxyFactoriesMutex.Unlock()
+    proxyFactories[type_] = factory
+}
+
+func findProxyFactory(type_ reflect.Type) (ProxyFactory, bool) {
+    proxyFactoriesMutex.RLock()
+    defer proxyFactoriesMutex.RUnlock()
+    factory, ok := proxyFactories[type_]
+    return factory, ok
+}
+
+// Interceptors of the bean, from the outermost to the innermost
+type InterceptorChain struct {
+    logger       logCtx.NamedLogger
+    interceptors []MethodInterceptor
+}
+
+func newInterceptorChain() *InterceptorChain {
+    return &InterceptorChain{
+        logger: logCtx.Get("IOC.InterceptorChain"),
+    }
+}
+
+// Invokes the interceptors and then the call, which calls the method of the target
+func (chain *InterceptorChain) Invoke(
+    target interface{},
+    method string,
+    args []interface{},
+    call func(args []interface{}) []interface{},
+) ([]interface{}, error) {
+    return (&invocation{
+        chain:  chain,
+        target: target,
+        method: method,
+        args:   args,
+        call:   call,
+    }).Proceed()
+}
+
+// Logs the error of the interceptors for the method without the error result.
+// Called by the generated proxies, which return zero values from the method then.
+func (chain *InterceptorChain) DropError(method string, e error) {
+    chain.logger.WithFields(log.Fields{
+        "method": method,
+        "error":  e.Error(),
+    }).Error("Method cannot return the error of the interceptor, zero values are returned")
+}
+
+type invocation struct {
+    chain  *InterceptorChain
+    index  int
+    target interface{}
+    method string
+    args   []interface{}
+    call   func(args []interface{}) []interface{}
+}
+
+func (inv *invocation) Target() interface{} {
+    return inv.target
+}
+
+func (inv *invocation) Method() string {
+    return inv.method
+}
+
+func (inv *invocation) Args() []interface{} {
+    return inv.args
+}
+
+func (inv *invocation) Proceed() ([]interface{}, error) {
+    if inv.index < len(inv.chain.interceptors) {
+        next := *inv
+        next.index++
+        return inv.chain.interceptors[inv.index].Invoke(&next)
+    }
+    results := inv.call(inv.args)
+    if len(results) > 0 {
+        if e, ok := results[len(results)-1].(error); ok && e != nil {
+            return results, e
+        }
+    }
+    return results, nil
+}
+
+// Attaches the interceptors to the definitions they intercept
+func (ctx *contextImpl) bindInterceptors() error {
+    errs := &MultiError{}
+    intercepted := map[*beanDefinition]bool{}
+    for _, interceptor := range ctx.beanDefinitions.all() {
+        if len(interceptor.intercepts) == 0 {
+            continue
+        }
+        if !interceptor.key.type_.Implements(reflect.TypeOf((*MethodInterceptor)(nil)).Elem()) {
+            errs.append(errors.New(interceptor.shortString() + " must implement MethodInterceptor"))
+            continue
+        }
+        for _, target := range ctx.beanDefinitions.all() {
+            if target == interceptor || !target.isInterceptedBy(interceptor) {
+                continue
+            }
+            target.interceptors = append(target.interceptors, interceptor)
+            intercepted[target] = true
+        }
+    }
+    for _, target := range ctx.beanDefinitions.all() {
+        if !intercepted[target] {
+            continue
+        }
+        factory, ok := findProxyFactory(target.key.type_)
+        if !ok {
+            errs.append(errors.New("Cannot intercept " + target.shortString() +
+                ": no proxy generated for " + target.key.type_.String() +
+                " (annotate it with //ioc:proxy and run ioc-proxygen)"))
+            continue
+        }
+        target := target
+        sort.SliceStable(target.interceptors, func(i, j int) bool {
+            return target.interceptors[i].priority > target.interceptors[j].priority
+        })
+        target.intercept = func(res *resolution, instance interface{}) (interface{}, error) {
+            return ctx.applyInterceptors(res, target, factory, instance)
+        }
+        ctx.logger.WithFields(log.Fields{
+            "beanDef":      target.shortString(),
+            "interceptors": len(target.interceptors),
+        }).Info("Interceptors bound")
+    }
+    return errs.errorOrNil()
+}
+
+// Beans found by type are intercepted only if they are bound as interfaces,
+// the implementations bound by their own types have no proxies
+func (bd *beanDefinition) isInterceptedBy(interceptor *beanDefinition) bool {
+    for _, lookup := range interceptor.intercepts {
+        if !isDefinitionSuitable(bd, lookup) {
+            continue
+        }
+        if lookup.isQualified() || bd.key.type_.Kind() == reflect.Interface {
+            return true
+        }
+    }
+    return false
+}
+
+// Wraps the instance by the proxy calling the interceptors, which are already instantiated
+func (ctx *contextImpl) applyInterceptors(
+    res *resolution,
+    definition *beanDefinition,
+    factory ProxyFactory,
+    instance interface{},
+) (interface{}, error) {
+    chain := newInterceptorChain()
+    for _, interceptor := range definition.interceptors {
+        found, ok := res.byDefinition[interceptor]
+        if !ok {
+            return nil, errors.New("Interceptor " + interceptor.shortString() + " is not instantiated")
+        }
+        chain.interceptors = append(chain.interceptors, found.instance.(MethodInterceptor))
+    }
+    return factory(instance, chain), nil
+}
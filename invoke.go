@@ -0,0 +1,41 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+    "reflect"
+)
+
+func (ctx *contextImpl) Invoke(fn interface{}) ([]interface{}, error) {
+    factory := newBeanFactory(fn, false)
+    if e := ctx.beanFactoryValidator.validateIn(factory); e != nil {
+        return nil, errors.Wrap(e, "Cannot invoke function")
+    }
+    dependencies, paramTypes := factory.collectDependencies()
+    definition := &beanDefinition{
+        key:          &bindKey{type_: factory.type_},
+        dependencies: dependencies,
+        paramTypes:   paramTypes,
+        factory:      factory,
+    }
+    snapshot, e := ctx.snapshotBuilt("invoke function", nil)
+    if e != nil {
+        return nil, e
+    }
+    params, e := ctx.resolveParams(snapshot, definition)
+    if e != nil {
+        return nil, errors.Wrap(e, "Cannot invoke function "+factory.type_.String())
+    }
+
+    results := reflect.ValueOf(fn).Call(params)
+    res := make([]interface{}, len(results))
+    for i, result := range results {
+        res[i] = result.Interface()
+    }
+    errorType := reflect.TypeOf((*error)(nil)).Elem()
+    if numOut := factory.type_.NumOut(); numOut > 0 && factory.type_.Out(numOut-1) == errorType {
+        if e, ok := res[numOut-1].(error); ok && e != nil {
+            return res, e
+        }
+    }
+    return res, nil
+}
@@ -0,0 +1,22 @@
+package ioctest
+
+import (
+    "errors"
+)
+
+type mapPropertySource map[string]string
+
+func (m *mapPropertySource) Get(key string) (string, error) {
+    if v, ok := (*m)[key]; ok {
+        return v, nil
+    }
+    return "", errors.New("Cannot find property " + key)
+}
+
+func (m *mapPropertySource) GetAll() map[string]string {
+    res := make(map[string]string, len(*m))
+    for k, v := range *m {
+        res[k] = v
+    }
+    return res
+}
@@ -0,0 +1,139 @@
+// Package ioctest helps to write tests against a wired pp_ioc.Context
+package ioctest
+
+import (
+    "errors"
+    ioc "github.com/wlad031/pp-ioc"
+    "reflect"
+    "testing"
+)
+
+// Qualifier of the property source created by MockProperties
+const MockPropertiesBeanName = "ioctest.MockProperties"
+
+// Wrapper of the context which fails the test on any error
+// and closes the context automatically when the test ends
+type TestContext struct {
+    t          testing.TB
+    ctx        ioc.Context
+    properties mapPropertySource
+}
+
+// Creates the context and registers the configurations in it.
+// The context is closed by t.Cleanup.
+func NewTestContext(t testing.TB, configurations ...ioc.Configuration) *TestContext {
+    t.Helper()
+    tc := &TestContext{
+        t:   t,
+        ctx: ioc.NewContext(),
+    }
+    if e := tc.ctx.Register(configurations...); e != nil {
+        t.Fatalf("Cannot register configurations: %v", e)
+    }
+    t.Cleanup(func() {
+        if e := tc.ctx.Close(); e != nil {
+            t.Errorf("Cannot close context: %v", e)
+        }
+    })
+    return tc
+}
+
+// Returns the wrapped context
+func (tc *TestContext) Context() ioc.Context {
+    return tc.ctx
+}
+
+// Replaces the beans matching the qualifier or type by the instance, see ioc.Context.Override
+func (tc *TestContext) Override(qualifierOrType interface{}, instance interface{}) *TestContext {
+    tc.t.Helper()
+    if e := tc.ctx.Override(qualifierOrType, instance); e != nil {
+        tc.t.Fatalf("Cannot override bean %v: %v", qualifierOrType, e)
+    }
+    return tc
+}
+
+// Adds the properties which take precedence over all other property sources,
+// since the source of the mocks has ioc.PropertySourceLowestPriority
+func (tc *TestContext) MockProperties(properties map[string]string) *TestContext {
+    if tc.properties == nil {
+        tc.properties = mapPropertySource{}
+        tc.ctx.NewPropertySourceBinder().
+            Priority(ioc.PropertySourceLowestPriority).
+            Qualifiers(MockPropertiesBeanName).
+            Factory(func() (*mapPropertySource, error) {
+                return &tc.properties, nil
+            })
+    }
+    for k, v := range properties {
+        tc.properties[k] = v
+    }
+    return tc
+}
+
+// Builds the whole context
+func (tc *TestContext) Build() *TestContext {
+    tc.t.Helper()
+    if e := tc.ctx.Build(); e != nil {
+        tc.t.Fatalf("Cannot build context: %v", e)
+    }
+    return tc
+}
+
+// Builds only the target bean and everything it depends on
+func (tc *TestContext) BuildFor(qualifierOrType interface{}) *TestContext {
+    tc.t.Helper()
+    if e := tc.ctx.BuildFor(qualifierOrType); e != nil {
+        tc.t.Fatalf("Cannot build context for %v: %v", qualifierOrType, e)
+    }
+    return tc
+}
+
+// Returns the bean by qualifier string, reflect.Type or a value of the needed type
+func (tc *TestContext) Bean(qualifierOrType interface{}) interface{} {
+    tc.t.Helper()
+    bean, e := tc.findBean(qualifierOrType)
+    if e != nil {
+        tc.t.Fatalf("Cannot find bean %v: %v", qualifierOrType, e)
+    }
+    return bean
+}
+
+// Finds the bean and stores it into the target pointer, the type is taken from the target:
+//
+//     var service *Service
+//     tc.Inject(&service)
+func (tc *TestContext) Inject(target interface{}) {
+    tc.t.Helper()
+    targetValue := reflect.ValueOf(target)
+    if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() {
+        tc.t.Fatalf("Cannot inject bean: pointer expected, got %T", target)
+    }
+    bean := tc.Bean(targetValue.Type().Elem())
+    targetValue.Elem().Set(reflect.ValueOf(bean))
+}
+
+func (tc *TestContext) AssertBeanExists(qualifierOrType interface{}) {
+    tc.t.Helper()
+    if _, e := tc.findBean(qualifierOrType); e != nil {
+        tc.t.Errorf("Expected bean %v to exist: %v", qualifierOrType, e)
+    }
+}
+
+func (tc *TestContext) AssertBeanNotExists(qualifierOrType interface{}) {
+    tc.t.Helper()
+    if _, e := tc.findBean(qualifierOrType); e == nil {
+        tc.t.Errorf("Expected bean %v not to exist", qualifierOrType)
+    }
+}
+
+func (tc *TestContext) findBean(qualifierOrType interface{}) (interface{}, error) {
+    switch v := qualifierOrType.(type) {
+    case nil:
+        return nil, errors.New("qualifier or type expected")
+    case string:
+        return tc.ctx.GetBeanByName(v)
+    case reflect.Type:
+        return tc.ctx.GetBeanByType(v)
+    }
+    return tc.ctx.GetBeanByType(reflect.TypeOf(qualifierOrType))
+}
@@ -0,0 +1,159 @@
+package ioctest_test
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "testing"
+
+    ioc "github.com/wlad031/pp-ioc"
+    "github.com/wlad031/pp-ioc/ioctest"
+)
+
+type fileProperties struct {
+    properties map[string]string
+}
+
+func (p *fileProperties) Get(key string) (string, error) {
+    if v, ok := p.properties[key]; ok {
+        return v, nil
+    }
+    return "", errors.New("Cannot find property " + key)
+}
+
+func (p *fileProperties) GetAll() map[string]string {
+    return p.properties
+}
+
+type repository interface {
+    Find(id string) string
+}
+
+type dbRepository struct {
+    url      string
+    disposed bool
+}
+
+func (r *dbRepository) Find(id string) string {
+    return r.url + "/" + id
+}
+
+func (r *dbRepository) Dispose() error {
+    r.disposed = true
+    return nil
+}
+
+type fakeRepository struct{}
+
+func (fakeRepository) Find(id string) string {
+    return "fake/" + id
+}
+
+type service struct {
+    repository repository
+}
+
+type report struct{}
+
+type appConfiguration struct {
+    repository *dbRepository
+}
+
+func (c *appConfiguration) Bind(ctx ioc.Context) error {
+    ctx.NewPropertySourceBinder().
+        Qualifiers("fileProperties").
+        Factory(func() *fileProperties {
+            return &fileProperties{properties: map[string]string{"db.url": "postgres://prod", "app.name": "app"}}
+        })
+    ctx.NewBinder().
+        Qualifiers("repository").
+        Factory(func(p struct {
+            Url string `value:"${db.url}"`
+        }) repository {
+            c.repository = &dbRepository{url: p.Url}
+            return c.repository
+        })
+    ctx.NewBinder().
+        Qualifiers("service").
+        Factory(func(p struct {
+            Repository repository `qualifier:"repository"`
+        }) *service {
+            return &service{repository: p.Repository}
+        })
+    ctx.NewBinder().
+        Qualifiers("report").
+        Factory(func() (*report, error) {
+            return nil, errors.New("Report is not available in tests")
+        })
+    return nil
+}
+
+// Records the failures instead of failing the test
+type recordingT struct {
+    testing.TB
+    failures []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+    t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func TestMockPropertiesOverrideOtherSources(t *testing.T) {
+    tc := ioctest.NewTestContext(t, &appConfiguration{}).
+        MockProperties(map[string]string{"db.url": "postgres://test"}).
+        BuildFor("service")
+
+    var s *service
+    tc.Inject(&s)
+    if res := s.repository.Find("1"); res != "postgres://test/1" {
+        t.Errorf("Expected the mocked url, got %s", res)
+    }
+    if name := tc.Context().GetEnvironment().GetPropertyOrDefault("app.name", ""); name != "app" {
+        t.Errorf("Expected the property of the other source, got %s", name)
+    }
+}
+
+func TestOverrideReplacesBean(t *testing.T) {
+    tc := ioctest.NewTestContext(t, &appConfiguration{}).
+        Override("repository", fakeRepository{}).
+        BuildFor("service")
+
+    s := tc.Bean("service").(*service)
+    if res := s.repository.Find("1"); res != "fake/1" {
+        t.Errorf("Expected the fake repository, got %s", res)
+    }
+}
+
+func TestBuildForSkipsUnrelatedBeans(t *testing.T) {
+    tc := ioctest.NewTestContext(t, &appConfiguration{}).BuildFor("service")
+
+    tc.AssertBeanExists("repository")
+    tc.AssertBeanExists(reflect.TypeOf((*service)(nil)))
+    tc.AssertBeanNotExists("report")
+}
+
+func TestAssertionsReportFailures(t *testing.T) {
+    recorder := &recordingT{TB: t}
+    tc := ioctest.NewTestContext(recorder, &appConfiguration{}).BuildFor("service")
+
+    tc.AssertBeanExists("report")
+    tc.AssertBeanNotExists("service")
+    if len(recorder.failures) != 2 {
+        t.Errorf("Expected both assertions to fail, got %v", recorder.failures)
+    }
+}
+
+func TestContextIsClosedOnCleanup(t *testing.T) {
+    configuration := &appConfiguration{}
+    t.Run("test", func(t *testing.T) {
+        ioctest.NewTestContext(t, configuration).BuildFor("repository")
+        if configuration.repository.disposed {
+            t.Error("Expected the repository not to be disposed before the test ends")
+        }
+    })
+    if !configuration.repository.disposed {
+        t.Error("Expected the repository to be disposed when the test ends")
+    }
+}
@@ -0,0 +1,111 @@
+package pp_ioc
+
+import (
+    "context"
+    "github.com/pkg/errors"
+    log "github.com/sirupsen/logrus"
+    "sort"
+    "time"
+)
+
+// Time given to the Lifecycle beans to stop when the context is closed
+const DefaultStopTimeout = 30 * time.Second
+
+// Beans implementing this interface are started by Context.Start and stopped by Context.Stop
+type Lifecycle interface {
+    Start(ctx context.Context) error
+    Stop(ctx context.Context) error
+    IsRunning() bool
+}
+
+// Lifecycle bean with the phase. Beans are started in ascending order of their phases
+// and stopped in descending one. Beans without a phase are in phase 0.
+type Phased interface {
+    Phase() int
+}
+
+func phaseOf(b *bean) int {
+    if phased, ok := b.instance.(Phased); ok {
+        return phased.Phase()
+    }
+    return 0
+}
+
+func (ctx *contextImpl) Start() error {
+    res, e := ctx.snapshotBuilt("start the context", nil)
+    if e != nil {
+        return e
+    }
+    var lifecycles []*bean
+    for _, bean := range res.beans {
+        if bean.definition.isLifecycle() {
+            lifecycles = append(lifecycles, bean)
+        }
+    }
+    sort.SliceStable(lifecycles, func(i, j int) bool {
+        return phaseOf(lifecycles[i]) < phaseOf(lifecycles[j])
+    })
+
+    ctx.lifecycleMutex.Lock()
+    defer ctx.lifecycleMutex.Unlock()
+    ctx.logger.Info("Starting the context...")
+    for _, bean := range lifecycles {
+        lifecycle := bean.instance.(Lifecycle)
+        if lifecycle.IsRunning() {
+            continue
+        }
+        if e := lifecycle.Start(context.Background()); e != nil {
+            e = errors.Wrap(e, "Cannot start "+bean.definition.shortString())
+            ctx.logger.WithFields(log.Fields{
+                "beanDef": bean.definition.shortString(),
+                "error":   e,
+            }).Error("Start failed, stopping already started beans")
+            errs := &MultiError{}
+            errs.append(e)
+            errs.append(ctx.stop(DefaultStopTimeout))
+            return errs.errorOrNil()
+        }
+        ctx.started = append(ctx.started, bean)
+        ctx.logger.WithFields(log.Fields{
+            "beanDef": bean.definition.shortString(),
+            "phase":   phaseOf(bean),
+        }).Info("Bean started")
+    }
+    return nil
+}
+
+func (ctx *contextImpl) Stop(timeout time.Duration) error {
+    ctx.lifecycleMutex.Lock()
+    defer ctx.lifecycleMutex.Unlock()
+    return ctx.stop(timeout)
+}
+
+// Must be called with the lifecycle lock held
+func (ctx *contextImpl) stop(timeout time.Duration) error {
+    if len(ctx.started) == 0 {
+        return nil
+    }
+    ctx.logger.Info("Stopping the context...")
+    stopCtx, cancel := context.WithTimeout(context.Background(), timeout)
+    defer cancel()
+
+    // started beans are already ordered by phase, so they are stopped in reverse
+    errs := &MultiError{}
+    for i := len(ctx.started) - 1; i >= 0; i-- {
+        bean := ctx.started[i]
+        lifecycle := bean.instance.(Lifecycle)
+        if !lifecycle.IsRunning() {
+            continue
+        }
+        if e := lifecycle.Stop(stopCtx); e != nil {
+            errs.append(errors.Wrap(e, "Cannot stop "+bean.definition.shortString()))
+            continue
+        }
+        ctx.logger.WithFields(log.Fields{
+            "beanDef": bean.definition.shortString(),
+            "phase":   phaseOf(bean),
+        }).Info("Bean stopped")
+    }
+    ctx.started = nil
+    return errs.errorOrNil()
+}
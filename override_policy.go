@@ -0,0 +1,99 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+    log "github.com/sirupsen/logrus"
+)
+
+// Decides what happens when two bean definitions have the same qualifiers and type
+type OverridePolicy int
+
+const (
+    // Duplicate definitions cause an error, unless one of them is marked with Binder.Overrides
+    OverridePolicyError OverridePolicy = iota
+    // The definition with the highest priority wins
+    OverridePolicyHighestPriority
+    // The definition bound last wins
+    OverridePolicyLastWins
+)
+
+func (op OverridePolicy) String() string {
+    switch op {
+    case OverridePolicyError:
+        return "Error"
+    case OverridePolicyHighestPriority:
+        return "HighestPriority"
+    case OverridePolicyLastWins:
+        return "LastWins"
+    }
+    return "Unknown policy"
+}
+
+func isSameDefinitionKey(bd1 *beanDefinition, bd2 *beanDefinition) bool {
+    return bd1.key.type_ == bd2.key.type_ &&
+        isSameQualifierSet(bd1.key.qualifiers, bd2.key.qualifiers) &&
+        bd1.key.hasSameMarkers(bd2.key)
+}
+
+// Checks whether both lists contain the same qualifiers regardless of their order and repetitions
+func isSameQualifierSet(qualifiers1 []string, qualifiers2 []string) bool {
+    set := make(map[string]bool, len(qualifiers1))
+    for _, qualifier := range qualifiers1 {
+        set[qualifier] = false
+    }
+    for _, qualifier := range qualifiers2 {
+        if _, ok := set[qualifier]; !ok {
+            return false
+        }
+        set[qualifier] = true
+    }
+    for _, found := range set {
+        if !found {
+            return false
+        }
+    }
+    return true
+}
+
+// Adds the definition resolving the duplicates according to the override policy
+func (ctx *contextImpl) addDefinition(definition *beanDefinition) error {
+    existing := ctx.beanDefinitions.findSameKey(definition)
+    if existing == nil {
+        ctx.beanDefinitions.add(definition)
+        return nil
+    }
+
+    replace, e := ctx.shouldReplace(existing, definition)
+    if e != nil {
+        return e
+    }
+    winner, loser := existing, definition
+    if replace {
+        ctx.beanDefinitions.remove(existing)
+        ctx.beanDefinitions.add(definition)
+        winner, loser = definition, existing
+    }
+    ctx.logger.WithFields(log.Fields{
+        "winner": winner.String(),
+        "loser":  loser.String(),
+        "policy": ctx.overridePolicy.String(),
+        "module": definition.module,
+    }).Info("Bean definition overridden")
+    return nil
+}
+
+func (ctx *contextImpl) shouldReplace(existing *beanDefinition, definition *beanDefinition) (bool, error) {
+    if definition.overrides != existing.overrides {
+        return definition.overrides, nil
+    }
+    switch ctx.overridePolicy {
+    case OverridePolicyHighestPriority:
+        if definition.priority != existing.priority {
+            return definition.priority > existing.priority, nil
+        }
+    case OverridePolicyLastWins:
+        return true, nil
+    }
+    return false, errors.New("Duplicate bean definition " + definition.shortString() +
+        ", mark the intentional replacement with Binder.Overrides()")
+}
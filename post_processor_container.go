@@ -17,15 +17,9 @@ func newPostProcessorContainer() *postProcessorContainer {
     }
 }
 
-func (container *postProcessorContainer) iterate() <-chan PostProcessor {
-    c := make(chan PostProcessor)
-    go func() {
-        for _, v := range container.ls {
-            c <- v
-        }
-        close(c)
-    }()
-    return c
+// Returns the underlying slice, callers must not modify it
+func (container *postProcessorContainer) all() []PostProcessor {
+    return container.ls
 }
 
 func (container *postProcessorContainer) add(b *bean) error {
@@ -6,9 +6,12 @@ const (
     DefaultPriority = 0
     ContextPriority = 1_000_000
 
+    // Property sources with lower priorities override the properties of the ones with higher
     PropertySourceHighestPriority = 900_000
     PropertySourceLowestPriority = 899_000
 
+    PropertyDecryptorPriority = 850_000
+
     EnvironmentPriority = 800_000
 
     HighestPriority = 500_000
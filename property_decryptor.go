@@ -0,0 +1,30 @@
+package pp_ioc
+
+import (
+    "strings"
+)
+
+const (
+    EncryptedValuePrefix = "ENC("
+    EncryptedValueSuffix = ")"
+)
+
+// Decrypts property values written as ENC(...).
+// Beans implementing this interface are registered in the Environment automatically.
+type PropertyDecryptor interface {
+    // Receives the value without ENC( and ) and returns the plain text
+    Decrypt(cipherText string) (string, error)
+}
+
+func isEncryptedValue(value string) bool {
+    return strings.HasPrefix(value, EncryptedValuePrefix) &&
+        strings.HasSuffix(value, EncryptedValueSuffix)
+}
+
+func unwrapEncryptedValue(value string) string {
+    return value[len(EncryptedValuePrefix) : len(value)-len(EncryptedValueSuffix)]
+}
+
+func wrapEncryptedValue(cipherText string) string {
+    return EncryptedValuePrefix + cipherText + EncryptedValueSuffix
+}
@@ -0,0 +1,107 @@
+package pp_ioc
+
+import (
+    "bufio"
+    "encoding/json"
+    "io"
+    "sort"
+    "strings"
+)
+
+// Description of the property injected into some bean field
+type PropertyMetadata struct {
+    Key         string   `json:"key"`
+    Type        string   `json:"type"`
+    Default     string   `json:"default,omitempty"`
+    HasDefault  bool     `json:"hasDefault"`
+    Required    bool     `json:"required"`
+    Constraints []string `json:"constraints,omitempty"`
+    Bean        string   `json:"bean"`
+    Field       string   `json:"field"`
+    Description string   `json:"description,omitempty"`
+}
+
+func newPropertyMetadata(definition *beanDefinition, dependency *dependency) PropertyMetadata {
+    metadata := PropertyMetadata{
+        Key:         dependency.qualifier,
+        Type:        dependency.type_.String(),
+        Default:     dependency.valueProvider.defaultValue,
+        HasDefault:  dependency.valueProvider.hasDefault,
+        Required:    !dependency.valueProvider.hasDefault,
+        Bean:        definition.shortString(),
+        Field:       dependency.name,
+        Description: dependency.description,
+    }
+    for _, constraint := range dependency.constraints {
+        if constraint.name == ConstraintRequired {
+            metadata.Required = true
+        }
+        metadata.Constraints = append(metadata.Constraints, constraint.String())
+    }
+    return metadata
+}
+
+func sortPropertyMetadata(metadata []PropertyMetadata) {
+    sort.SliceStable(metadata, func(i, j int) bool {
+        if metadata[i].Key != metadata[j].Key {
+            return metadata[i].Key < metadata[j].Key
+        }
+        return metadata[i].Bean < metadata[j].Bean
+    })
+}
+
+// Writes the metadata as indented JSON array
+func WritePropertyMetadata(w io.Writer, metadata []PropertyMetadata) error {
+    encoder := json.NewEncoder(w)
+    encoder.SetIndent("", "  ")
+    if metadata == nil {
+        metadata = []PropertyMetadata{}
+    }
+    return encoder.Encode(metadata)
+}
+
+// Writes the sample .properties file. Properties without defaults are written
+// uncommented and empty, the ones with defaults are commented out.
+func WriteSampleProperties(w io.Writer, metadata []PropertyMetadata) error {
+    byKey := map[string][]PropertyMetadata{}
+    var keys []string
+    for _, m := range metadata {
+        if _, ok := byKey[m.Key]; !ok {
+            keys = append(keys, m.Key)
+        }
+        byKey[m.Key] = append(byKey[m.Key], m)
+    }
+    sort.Strings(keys)
+
+    out := bufio.NewWriter(w)
+    for i, key := range keys {
+        entries := byKey[key]
+        if i > 0 {
+            _, _ = out.WriteString("\n")
+        }
+        var beans []string
+        var descriptions []string
+        for _, m := range entries {
+            beans = append(beans, m.Bean+"."+m.Field)
+            if m.Description != "" {
+                descriptions = append(descriptions, m.Description)
+            }
+        }
+        for _, description := range descriptions {
+            _, _ = out.WriteString("# " + description + "\n")
+        }
+        first := entries[0]
+        comment := "# type: " + first.Type
+        if len(first.Constraints) > 0 {
+            comment += ", constraints: " + strings.Join(first.Constraints, ",")
+        }
+        _, _ = out.WriteString(comment + "\n")
+        _, _ = out.WriteString("# used by: " + strings.Join(beans, ", ") + "\n")
+        if first.HasDefault {
+            _, _ = out.WriteString("#" + key + "=" + first.Default + "\n")
+        } else {
+            _, _ = out.WriteString(key + "=\n")
+        }
+    }
+    return out.Flush()
+}
@@ -0,0 +1,40 @@
+package pp_ioc
+
+import (
+    "context"
+)
+
+// Injected instead of the bean to look it up lazily, by the qualifier
+// of the field. The only way for singletons to use request scoped beans.
+type Provider interface {
+    // Returns the bean, request scoped beans are taken from the scope started by WithScope
+    Get(ctx context.Context) (interface{}, error)
+}
+
+type providerImpl struct {
+    ctx        *contextImpl
+    requester  *beanDefinition
+    dependency *dependency
+}
+
+func (p *providerImpl) Get(ctx context.Context) (interface{}, error) {
+    res, e := p.ctx.snapshotBuilt("get bean by provider", requestScopeFrom(ctx))
+    if e != nil {
+        return nil, e
+    }
+    var found []*beanDefinition
+    for _, definition := range p.ctx.beanDefinitions.ls {
+        if definition.isSuitableForDependencyByQualifier(p.dependency) {
+            found = append(found, definition)
+        }
+    }
+    switch len(found) {
+    case 0:
+        return nil, newMissingBeanError(p.requester, p.dependency, p.ctx.beanDefinitions.ls)
+    case 1:
+    default:
+        return nil, newAmbiguousBeanError(p.requester, p.dependency, found)
+    }
+
+    return p.ctx.getDefinitionInstance(res, found[0])
+}
@@ -0,0 +1,86 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+    "reflect"
+)
+
+// Embedded into the wrapper struct to inject the bean qualified by marker types
+// (see Binder.QualifiedBy). Other embedded fields of the wrapper are the markers,
+// the bean is put into the Bean field:
+//
+//     type ReplicaDB struct {
+//         ioc.Qualified
+//         ReadReplica
+//         Bean *sql.DB
+//     }
+//
+// Qualifier and inject tags of the wrapper field are matched too.
+type Qualified struct{}
+
+const qualifiedBeanField = "Bean"
+
+// Marker types are always structs, so ReadReplica{} and &ReadReplica{} are the same marker
+func markerType(type_ reflect.Type) reflect.Type {
+    for type_.Kind() == reflect.Ptr {
+        type_ = type_.Elem()
+    }
+    return type_
+}
+
+func isQualifiedWrapper(type_ reflect.Type) bool {
+    if type_.Kind() != reflect.Struct {
+        return false
+    }
+    field, ok := type_.FieldByName("Qualified")
+    return ok && field.Anonymous && field.Type == reflect.TypeOf(Qualified{})
+}
+
+func validateQualifiedWrapper(type_ reflect.Type) error {
+    if _, ok := type_.FieldByName(qualifiedBeanField); !ok {
+        return errors.New("Qualified wrapper " + type_.String() + " must have " +
+            qualifiedBeanField + " field")
+    }
+    if markers, _ := parseQualifiedWrapper(type_); len(markers) == 0 {
+        return errors.New("Qualified wrapper " + type_.String() + " must embed at least one marker")
+    }
+    return nil
+}
+
+// Returns the marker types and the bean type of the wrapper
+func parseQualifiedWrapper(type_ reflect.Type) (markers []reflect.Type, beanType reflect.Type) {
+    for i := 0; i < type_.NumField(); i++ {
+        field := type_.Field(i)
+        if field.Anonymous && field.Type != reflect.TypeOf(Qualified{}) {
+            markers = append(markers, markerType(field.Type))
+        }
+        if field.Name == qualifiedBeanField {
+            beanType = field.Type
+        }
+    }
+    return markers, beanType
+}
+
+func newQualifiedDependency(
+    name string,
+    qualifier string,
+    hasQualifier bool,
+    wrapperType reflect.Type,
+    index uint16,
+) *dependency {
+    markers, beanType := parseQualifiedWrapper(wrapperType)
+    dependency := newBeanDependency(name, qualifier, hasQualifier, beanType, index)
+    dependency.markers = markers
+    dependency.wrapperType = wrapperType
+    return dependency
+}
+
+// Puts the bean into the wrapper, if the dependency has one
+func (d *dependency) wrap(instance reflect.Value) reflect.Value {
+    if d.wrapperType == nil {
+        return instance
+    }
+    wrapper := reflect.New(d.wrapperType).Elem()
+    wrapper.FieldByName(qualifiedBeanField).Set(instance)
+    return wrapper
+}
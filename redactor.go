@@ -0,0 +1,120 @@
+package pp_ioc
+
+import (
+    "math"
+    "path"
+    "regexp"
+    "strings"
+)
+
+// Value printed instead of the sensitive ones
+const RedactedValue = "******"
+
+// Key patterns which are considered sensitive by default.
+// Patterns are matched case-insensitively with path.Match rules.
+var DefaultSensitiveKeyPatterns = []string{
+    "*password*",
+    "*passwd*",
+    "*secret*",
+    "*token*",
+    "*key*",
+    "*credential*",
+}
+
+// Property source that knows which of its keys are sensitive
+type SensitivePropertySource interface {
+    IsSensitive(key string) bool
+}
+
+var sensitiveValuePatterns = []*regexp.Regexp{
+    regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`),
+    regexp.MustCompile(`^[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}$`), // JWT
+    regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://[^/:@\s]+:[^/@\s]+@`),               // URL with credentials
+    regexp.MustCompile(`(?i)^bearer\s+\S+`),
+    regexp.MustCompile(`^AKIA[0-9A-Z]{16}$`), // AWS access key id
+}
+
+const (
+    highEntropyMinLength = 32
+    highEntropyMinBits   = 4.0
+)
+
+// Decides whether a property is sensitive and masks its value
+type Redactor struct {
+    keyPatterns  []string
+    detectValues bool
+}
+
+// Creates the Redactor with DefaultSensitiveKeyPatterns and value-based detection enabled
+func NewRedactor() *Redactor {
+    return &Redactor{
+        keyPatterns:  append([]string{}, DefaultSensitiveKeyPatterns...),
+        detectValues: true,
+    }
+}
+
+// Adds the key patterns to the already configured ones
+func (r *Redactor) KeyPatterns(patterns ...string) *Redactor {
+    for _, pattern := range patterns {
+        r.keyPatterns = append(r.keyPatterns, strings.ToLower(pattern))
+    }
+    return r
+}
+
+// Enables or disables detection of secrets by the property value itself
+func (r *Redactor) DetectValues(detectValues bool) *Redactor {
+    r.detectValues = detectValues
+    return r
+}
+
+func (r *Redactor) IsSensitiveKey(key string) bool {
+    key = strings.ToLower(key)
+    for _, pattern := range r.keyPatterns {
+        if matched, e := path.Match(pattern, key); e == nil && matched {
+            return true
+        }
+    }
+    return false
+}
+
+func (r *Redactor) IsSensitiveValue(value string) bool {
+    if !r.detectValues {
+        return false
+    }
+    for _, pattern := range sensitiveValuePatterns {
+        if pattern.MatchString(value) {
+            return true
+        }
+    }
+    return len(value) >= highEntropyMinLength &&
+        !strings.ContainsAny(value, " /\\") &&
+        shannonEntropy(value) >= highEntropyMinBits
+}
+
+func (r *Redactor) IsSensitive(entry PropertyEntry) bool {
+    return entry.Sensitive || r.IsSensitiveKey(entry.Key) || r.IsSensitiveValue(entry.Value)
+}
+
+// Returns the value of the property or RedactedValue if the property is sensitive
+func (r *Redactor) Redact(entry PropertyEntry) string {
+    if r.IsSensitive(entry) {
+        return RedactedValue
+    }
+    return entry.Value
+}
+
+// Returns the number of bits of entropy per character
+func shannonEntropy(s string) float64 {
+    frequencies := map[rune]float64{}
+    var length float64
+    for _, r := range s {
+        frequencies[r]++
+        length++
+    }
+    var entropy float64
+    for _, count := range frequencies {
+        p := count / length
+        entropy -= p * math.Log2(p)
+    }
+    return entropy
+}
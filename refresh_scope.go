@@ -0,0 +1,131 @@
+package pp_ioc
+
+import (
+    "context"
+    "github.com/pkg/errors"
+    log "github.com/sirupsen/logrus"
+    "sort"
+)
+
+// Rebuilds the refresh-scoped beans which inject any of the changed
+// properties, together with all the beans that depend on them
+func (ctx *contextImpl) onPropertiesChanged(event PropertiesChanged) {
+    ctx.buildMutex.Lock()
+    defer ctx.buildMutex.Unlock()
+    ctx.mutex.RLock()
+    initialized := ctx.initialized
+    ctx.mutex.RUnlock()
+    if !initialized {
+        return
+    }
+    keys := map[string]bool{}
+    for _, key := range event.Keys {
+        keys[key] = true
+    }
+
+    var indexes []int
+    for _, definition := range ctx.beanDefinitions.all() {
+        if definition.scope == ScopeRefresh && definition.injectsAnyProperty(keys) {
+            indexes = append(indexes, definition.graphIndex)
+        }
+    }
+    if len(indexes) == 0 {
+        return
+    }
+    if e := ctx.rebuildBeans(indexes); e != nil {
+        ctx.logger.WithFields(log.Fields{
+            "error": e.Error(),
+        }).Error("Cannot rebuild refresh scoped beans")
+    }
+}
+
+func (ctx *contextImpl) rebuildBeans(indexes []int) error {
+    var replaced, rebuilt []*bean
+    errs := &MultiError{}
+    for _, definition := range ctx.graph.dependentsOf(indexes) {
+        if definition.isCreatedOnLookup() {
+            // the next lookup creates the instance with the new properties,
+            // the forgotten lazy singleton is released as the replaced one
+            if old := definition.reset(); old != nil && definition.lazy {
+                replaced = append(replaced, old)
+                rebuilt = append(rebuilt, nil)
+            }
+            continue
+        }
+        if e := ctx.validateDefinitionValues(definition); e != nil {
+            errs.append(e)
+            break
+        }
+        definition.reset()
+        // concurrent lookups only read the container, so it is safe without the lock
+        bean, e := ctx.instantiateDefinition(definition)
+        if e != nil {
+            errs.append(e)
+            break
+        }
+        ctx.mutex.Lock()
+        old := ctx.container.replace(bean)
+        ctx.mutex.Unlock()
+        if old != nil {
+            replaced = append(replaced, old)
+            rebuilt = append(rebuilt, bean)
+        }
+        ctx.logger.WithFields(log.Fields{
+            "beanDef": definition.shortString(),
+        }).Info("Bean rebuilt")
+    }
+    // the beans replaced before the failure are already unreachable, so they are released anyway
+    errs.append(ctx.releaseReplacedBeans(replaced, rebuilt))
+    return errs.errorOrNil()
+}
+
+// Stops and disposes the replaced beans in the reverse order of their rebuilding,
+// then starts the rebuilt beans which replaced the started ones. The rebuilt bean
+// is nil for the lazy singleton, which is created on the next lookup.
+func (ctx *contextImpl) releaseReplacedBeans(replaced []*bean, rebuilt []*bean) error {
+    ctx.lifecycleMutex.Lock()
+    defer ctx.lifecycleMutex.Unlock()
+    errs := &MultiError{}
+    restart := make([]bool, len(replaced))
+    for i := len(replaced) - 1; i >= 0; i-- {
+        old := replaced[i]
+        for j, started := range ctx.started {
+            if started != old {
+                continue
+            }
+            ctx.started = append(ctx.started[:j], ctx.started[j+1:]...)
+            restart[i] = true
+            if lifecycle := old.instance.(Lifecycle); lifecycle.IsRunning() {
+                stopCtx, cancel := context.WithTimeout(context.Background(), DefaultStopTimeout)
+                if e := lifecycle.Stop(stopCtx); e != nil {
+                    errs.append(errors.Wrap(e, "Cannot stop replaced "+old.definition.shortString()))
+                }
+                cancel()
+            }
+            break
+        }
+        if disposer, ok := old.instance.(Disposer); ok {
+            if e := disposer.Dispose(); e != nil {
+                errs.append(errors.Wrap(e, "Cannot dispose replaced "+old.definition.shortString()))
+            }
+        }
+    }
+    for i, bean := range rebuilt {
+        if !restart[i] || !bean.definition.isLifecycle() {
+            continue
+        }
+        if e := bean.instance.(Lifecycle).Start(context.Background()); e != nil {
+            errs.append(errors.Wrap(e, "Cannot start rebuilt "+bean.definition.shortString()))
+            continue
+        }
+        ctx.started = append(ctx.started, bean)
+        ctx.logger.WithFields(log.Fields{
+            "beanDef": bean.definition.shortString(),
+        }).Info("Rebuilt bean started")
+    }
+    // started beans are stopped in reverse, so they must stay ordered by phase
+    sort.SliceStable(ctx.started, func(i, j int) bool {
+        return phaseOf(ctx.started[i]) < phaseOf(ctx.started[j])
+    })
+    return errs.errorOrNil()
+}
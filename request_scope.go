@@ -0,0 +1,101 @@
+package pp_ioc
+
+import (
+    "context"
+    "github.com/pkg/errors"
+    "sync"
+)
+
+type requestScopeKey struct{}
+
+type requestScope struct {
+    mutex sync.Mutex
+    beans map[*beanDefinition]*bean
+    order []*bean
+    ended bool
+}
+
+// Starts the request scope. Request scoped beans are created once per scope
+// and stored in the returned context. The returned function ends the scope,
+// disposing the Disposer beans in the reverse order of their creation.
+func WithScope(ctx context.Context) (context.Context, func() error) {
+    scope := &requestScope{
+        beans: map[*beanDefinition]*bean{},
+    }
+    return context.WithValue(ctx, requestScopeKey{}, scope), scope.end
+}
+
+func requestScopeFrom(ctx context.Context) *requestScope {
+    if ctx == nil {
+        return nil
+    }
+    scope, _ := ctx.Value(requestScopeKey{}).(*requestScope)
+    return scope
+}
+
+func (scope *requestScope) get(definition *beanDefinition) (*bean, error) {
+    scope.mutex.Lock()
+    defer scope.mutex.Unlock()
+    if scope.ended {
+        return nil, errors.New("Request scope has already ended")
+    }
+    return scope.beans[definition], nil
+}
+
+// Stores the bean, unless the bean of the same definition was stored concurrently
+func (scope *requestScope) put(b *bean) *bean {
+    scope.mutex.Lock()
+    defer scope.mutex.Unlock()
+    if existing, ok := scope.beans[b.definition]; ok {
+        return existing
+    }
+    scope.beans[b.definition] = b
+    scope.order = append(scope.order, b)
+    return b
+}
+
+func (scope *requestScope) end() error {
+    scope.mutex.Lock()
+    defer scope.mutex.Unlock()
+    if scope.ended {
+        return nil
+    }
+    scope.ended = true
+    errs := &MultiError{}
+    for i := len(scope.order) - 1; i >= 0; i-- {
+        if disposer, ok := scope.order[i].instance.(Disposer); ok {
+            if e := disposer.Dispose(); e != nil {
+                errs.append(errors.Wrap(e, "Cannot dispose "+scope.order[i].definition.shortString()))
+            }
+        }
+    }
+    scope.beans = nil
+    scope.order = nil
+    return errs.errorOrNil()
+}
+
+// Finds the request scoped definition suitable for the dependency
+func (ctx *contextImpl) findRequestScopedDefinition(dependency *dependency) *beanDefinition {
+    for _, definition := range ctx.beanDefinitions.ls {
+        if definition.scope == ScopeRequest && isDefinitionSuitable(definition, dependency) {
+            return definition
+        }
+    }
+    return nil
+}
+
+func (ctx *contextImpl) getRequestScopedBean(res *resolution, definition *beanDefinition) (*bean, error) {
+    b, e := res.requestScope.get(definition)
+    if e != nil || b != nil {
+        return b, e
+    }
+    params, e := ctx.resolveParams(res, definition)
+    if e != nil {
+        return nil, e
+    }
+    b, e = definition.createBean(res, params)
+    if e != nil {
+        return nil, e
+    }
+    return res.requestScope.put(b), nil
+}
@@ -0,0 +1,52 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+)
+
+// Snapshot of the context the dependencies are resolved against. Lookups take it
+// under the read lock and run the factories of scoped beans without the lock,
+// so the factories can use the context themselves. The container is copied
+// on write, so the snapshot stays valid while the beans are being rebuilt.
+type resolution struct {
+    beans        []*bean
+    byDefinition map[*beanDefinition]*bean
+    index        *definitionIndex
+    environment  Environment
+    requestScope *requestScope // nil outside of the scope started by WithScope
+}
+
+// Must be called with the read lock or the build lock held
+func (ctx *contextImpl) newResolution(requestScope *requestScope) *resolution {
+    return &resolution{
+        beans:        ctx.container.all(),
+        byDefinition: ctx.container.byDefinition,
+        index:        ctx.graph.index,
+        environment:  ctx.environment,
+        requestScope: requestScope,
+    }
+}
+
+// Checks whether the bean of the definition can be looked up in the snapshot:
+// it is instantiated or created on lookup. Request scoped beans are available
+// only within the request scope.
+func (res *resolution) hasBean(definition *beanDefinition) bool {
+    if definition.scope == ScopeRequest {
+        return res.requestScope != nil
+    }
+    if definition.isCreatedOnLookup() {
+        return true
+    }
+    _, ok := res.byDefinition[definition]
+    return ok
+}
+
+// Takes the snapshot if the context is built, otherwise returns the error
+func (ctx *contextImpl) snapshotBuilt(action string, requestScope *requestScope) (*resolution, error) {
+    ctx.mutex.RLock()
+    defer ctx.mutex.RUnlock()
+    if !ctx.initialized {
+        return nil, errors.New("Cannot " + action + ": the context is not built")
+    }
+    return ctx.newResolution(requestScope), nil
+}
@@ -0,0 +1,99 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+    log "github.com/sirupsen/logrus"
+    "reflect"
+    "strings"
+)
+
+// User-defined bean scope, e.g. tenant, job or session. Beans of the scope are not
+// instantiated by Build, they are requested from it every time they are looked up
+// or injected. Their instances are removed from the scope when the context is refreshed
+// or closed, and when the beans they depend on are rebuilt.
+type Scope interface {
+    // Returns the instance of the bean with the name, calling the factory
+    // if the scope does not have it yet
+    Get(name string, factory func() (interface{}, error)) (interface{}, error)
+    // Removes the instance of the bean with the name from the scope,
+    // returns the removed instance or nil
+    Remove(name string) interface{}
+}
+
+func (ctx *contextImpl) RegisterScope(name string, scope Scope) error {
+    if ctx.bound {
+        return errors.New("Scopes cannot be registered after the context is bound")
+    }
+    if scope == nil {
+        return errors.New("Invalid scope " + name + ": nil")
+    }
+    if _, e := FromString(name); e == nil {
+        return errors.New("Cannot register scope " + name + ": built-in scope")
+    }
+    key := strings.ToLower(name)
+    if _, ok := ctx.scopes[key]; ok {
+        return errors.New("Scope " + name + " is already registered")
+    }
+    ctx.scopes[key] = scope
+    ctx.logger.WithFields(log.Fields{
+        "scope": name,
+    }).Info("Scope registered")
+    return nil
+}
+
+// Finds the registered scope for the binder with the custom scope
+func (ctx *contextImpl) resolveCustomScope(binder *Binder) (Scope, error) {
+    if binder.scope != ScopeCustom {
+        return nil, nil
+    }
+    scope, ok := ctx.scopes[strings.ToLower(binder.scopeName)]
+    if !ok {
+        return nil, errors.New("Unknown bean scope " + binder.scopeName)
+    }
+    return scope, nil
+}
+
+// Returns the instance of the bean of the definition. Custom scoped beans are requested
+// from their scope, request scoped ones from the request scope of the resolution,
+// prototypes are created every time and lazy singletons on the first call.
+// Other beans are taken from the snapshot.
+func (ctx *contextImpl) getDefinitionInstance(res *resolution, definition *beanDefinition) (interface{}, error) {
+    resolveParams := func() ([]reflect.Value, error) {
+        return ctx.resolveParams(res, definition)
+    }
+    if definition.lazy {
+        b, e := definition.getLazyBean(res, resolveParams)
+        if e != nil {
+            return nil, e
+        }
+        return b.instance, nil
+    }
+    switch definition.scope {
+    case ScopePrototype:
+        params, e := resolveParams()
+        if e != nil {
+            return nil, e
+        }
+        b, e := definition.createBean(res, params)
+        if e != nil {
+            return nil, e
+        }
+        return b.instance, nil
+    case ScopeCustom:
+        return definition.getScopedInstance(res, resolveParams)
+    case ScopeRequest:
+        if res.requestScope == nil {
+            return nil, errors.New("Cannot get request scoped " + definition.shortString() +
+                ": the context has no scope started by WithScope")
+        }
+        b, e := ctx.getRequestScopedBean(res, definition)
+        if e != nil {
+            return nil, e
+        }
+        return b.instance, nil
+    }
+    if b, ok := res.byDefinition[definition]; ok {
+        return b.instance, nil
+    }
+    return nil, errors.New("Bean " + definition.shortString() + " is not instantiated")
+}
@@ -0,0 +1,100 @@
+package pp_ioc
+
+import (
+    "errors"
+    "sync"
+    "testing"
+)
+
+// Caches the instances by name while the job is active, like a real job or tenant scope
+type cachingScope struct {
+    mutex     sync.Mutex
+    active    bool
+    instances map[string]interface{}
+}
+
+func newCachingScope() *cachingScope {
+    return &cachingScope{instances: map[string]interface{}{}}
+}
+
+func (s *cachingScope) Get(name string, factory func() (interface{}, error)) (interface{}, error) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    if !s.active {
+        return nil, errors.New("No active job")
+    }
+    if instance, ok := s.instances[name]; ok {
+        return instance, nil
+    }
+    instance, e := factory()
+    if e != nil {
+        return nil, e
+    }
+    s.instances[name] = instance
+    return instance, nil
+}
+
+func (s *cachingScope) Remove(name string) interface{} {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    instance := s.instances[name]
+    delete(s.instances, name)
+    return instance
+}
+
+func (s *cachingScope) activate() {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    s.active = true
+}
+
+func getJob(t *testing.T, ctx Context) *testJob {
+    t.Helper()
+    job, e := ctx.GetBeanByName("job")
+    if e != nil {
+        t.Fatal(e)
+    }
+    return job.(*testJob)
+}
+
+func TestCustomScopedBeanIsNotCreatedByBuild(t *testing.T) {
+    scope := newCachingScope()
+    ctx, _ := newConcurrencyContextWithScope(t, scope)
+
+    if _, e := ctx.GetBeanByName("job"); e == nil {
+        t.Fatal("Expected the error of the inactive scope")
+    }
+    scope.activate()
+    if job := getJob(t, ctx); job != getJob(t, ctx) {
+        t.Error("Expected the instance cached by the scope")
+    }
+}
+
+func TestCustomScopedBeanIsRemovedOnPropertiesChange(t *testing.T) {
+    scope := newCachingScope()
+    scope.activate()
+    ctx, configuration := newConcurrencyContextWithScope(t, scope)
+
+    if url := getJob(t, ctx).repository.url; url != "url-0" {
+        t.Fatalf("Expected url-0, got %s", url)
+    }
+    configuration.properties.set("db.url", "url-1")
+    ctx.onPropertiesChanged(PropertiesChanged{Keys: []string{"db.url"}})
+    if url := getJob(t, ctx).repository.url; url != "url-1" {
+        t.Errorf("Expected the job with the rebuilt repository with url-1, got %s", url)
+    }
+}
+
+func TestCustomScopedBeanIsRemovedOnRefresh(t *testing.T) {
+    scope := newCachingScope()
+    scope.activate()
+    ctx, _ := newConcurrencyContextWithScope(t, scope)
+
+    job := getJob(t, ctx)
+    if e := ctx.Refresh(); e != nil {
+        t.Fatal(e)
+    }
+    if job == getJob(t, ctx) {
+        t.Error("Expected the new job instance after the refresh")
+    }
+}
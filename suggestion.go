@@ -0,0 +1,115 @@
+package pp_ioc
+
+import (
+    "reflect"
+    "strconv"
+)
+
+// Builds MissingBeanError with the definitions suitable by type
+// and the most similar qualifier or type as a suggestion
+func newMissingBeanError(
+    requester *beanDefinition,
+    dependency *dependency,
+    definitions []*beanDefinition,
+) *MissingBeanError {
+    err := &MissingBeanError{
+        Definition: definitionName(requester),
+        Dependency: dependency.String(),
+    }
+    for _, definition := range definitions {
+        if dependency.isQualified() && definition.isSuitableForDependencyByType(dependency) {
+            err.Candidates = append(err.Candidates, definition.shortString())
+        }
+    }
+
+    bestDistance := -1
+    suggest := func(target string, candidate string, suggestion string) {
+        distance := levenshtein(target, candidate)
+        if distance == 0 || distance > similarityThreshold(target) || distance >= len(target) {
+            return
+        }
+        if bestDistance < 0 || distance < bestDistance {
+            bestDistance = distance
+            err.Suggestion = suggestion
+        }
+    }
+    for _, definition := range definitions {
+        if dependency.hasQualifier {
+            for _, qualifier := range definition.key.qualifiers {
+                suggest(dependency.qualifier, qualifier, "qualifier "+strconv.Quote(qualifier))
+            }
+        } else {
+            suggest(baseTypeName(dependency.type_), baseTypeName(definition.key.type_),
+                "type "+definition.key.type_.String())
+        }
+    }
+    return err
+}
+
+func newAmbiguousBeanError(
+    requester *beanDefinition,
+    dependency *dependency,
+    candidates []*beanDefinition,
+) *AmbiguousBeanError {
+    err := &AmbiguousBeanError{
+        Definition: definitionName(requester),
+        Dependency: dependency.String(),
+    }
+    for _, candidate := range candidates {
+        err.Candidates = append(err.Candidates, candidate.shortString())
+    }
+    return err
+}
+
+func definitionName(definition *beanDefinition) string {
+    if definition == nil {
+        return ""
+    }
+    return definition.shortString()
+}
+
+// Type name without the pointer and the package, e.g. "Foo" for *pkg.Foo
+func baseTypeName(type_ reflect.Type) string {
+    for type_.Kind() == reflect.Ptr {
+        type_ = type_.Elem()
+    }
+    if type_.Name() != "" {
+        return type_.Name()
+    }
+    return type_.String()
+}
+
+func similarityThreshold(s string) int {
+    if threshold := len(s) / 3; threshold > 1 {
+        return threshold
+    }
+    return 1
+}
+
+func levenshtein(a string, b string) int {
+    ra, rb := []rune(a), []rune(b)
+    previous := make([]int, len(rb)+1)
+    current := make([]int, len(rb)+1)
+    for j := range previous {
+        previous[j] = j
+    }
+    for i := 1; i <= len(ra); i++ {
+        current[0] = i
+        for j := 1; j <= len(rb); j++ {
+            cost := 1
+            if ra[i-1] == rb[j-1] {
+                cost = 0
+            }
+            current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+        }
+        previous, current = current, previous
+    }
+    return previous[len(rb)]
+}
+
+func minInt(a int, b int) int {
+    if a < b {
+        return a
+    }
+    return b
+}
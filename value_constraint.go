@@ -0,0 +1,160 @@
+package pp_ioc
+
+import (
+    "github.com/pkg/errors"
+    "reflect"
+    "regexp"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+)
+
+const (
+    ConstraintRequired = "required"
+    ConstraintMin      = "min"
+    ConstraintMax      = "max"
+    ConstraintOneOf    = "oneof"
+    ConstraintRegex    = "regex"
+
+    constraintSep      = ","
+    constraintParamSep = "="
+)
+
+// Single constraint of the validate tag, e.g. min=1
+type valueConstraint struct {
+    name   string
+    param  string
+    number float64
+    values []string
+    regex  *regexp.Regexp
+}
+
+func (c *valueConstraint) String() string {
+    if c.param == "" {
+        return c.name
+    }
+    return c.name + constraintParamSep + c.param
+}
+
+// Parses the validate tag, e.g. `validate:"required,min=1,max=65535"`.
+// The regex constraint takes the rest of the tag, so it must be the last one.
+func parseConstraints(tag string) ([]*valueConstraint, error) {
+    var constraints []*valueConstraint
+    rest := strings.TrimSpace(tag)
+    for rest != "" {
+        var part string
+        if strings.HasPrefix(rest, ConstraintRegex+constraintParamSep) {
+            part, rest = rest, ""
+        } else if i := strings.Index(rest, constraintSep); i >= 0 {
+            part, rest = rest[:i], rest[i+1:]
+        } else {
+            part, rest = rest, ""
+        }
+        constraint, e := parseConstraint(strings.TrimSpace(part))
+        if e != nil {
+            return nil, e
+        }
+        constraints = append(constraints, constraint)
+    }
+    return constraints, nil
+}
+
+func parseConstraint(s string) (*valueConstraint, error) {
+    constraint := &valueConstraint{name: s}
+    if i := strings.Index(s, constraintParamSep); i >= 0 {
+        constraint.name, constraint.param = s[:i], s[i+1:]
+    }
+    switch constraint.name {
+    case ConstraintRequired:
+        if constraint.param != "" {
+            return nil, errors.New("Constraint " + ConstraintRequired + " has no parameters")
+        }
+    case ConstraintMin, ConstraintMax:
+        number, e := strconv.ParseFloat(constraint.param, 64)
+        if e != nil {
+            return nil, errors.Wrap(e, "Invalid parameter of constraint "+constraint.name)
+        }
+        constraint.number = number
+    case ConstraintOneOf:
+        constraint.values = strings.Fields(constraint.param)
+        if len(constraint.values) == 0 {
+            return nil, errors.New("Constraint " + ConstraintOneOf + " requires at least one value")
+        }
+    case ConstraintRegex:
+        regex, e := regexp.Compile(constraint.param)
+        if e != nil {
+            return nil, errors.Wrap(e, "Invalid parameter of constraint "+ConstraintRegex)
+        }
+        constraint.regex = regex
+    default:
+        return nil, errors.New("Unknown constraint " + constraint.name)
+    }
+    return constraint, nil
+}
+
+// Returns an error, if the constraint cannot check the values of this type
+func (c *valueConstraint) validateFor(type_ reflect.Type) error {
+    if (c.name == ConstraintMin || c.name == ConstraintMax) && !hasSize(type_.Kind()) {
+        return errors.New("Constraint " + c.name + " is not applicable to " + type_.String() +
+            ", only numbers and strings have a size")
+    }
+    return nil
+}
+
+// Checks the property value, rawValue is the value as it is written in the property source.
+// Returns the description of the violation or empty string.
+func (c *valueConstraint) check(rawValue string, value reflect.Value) string {
+    switch c.name {
+    case ConstraintRequired:
+        if rawValue == "" {
+            return "value is required"
+        }
+    case ConstraintMin:
+        if size, unit := valueSize(value); size < c.number {
+            return unit + " must be at least " + c.param
+        }
+    case ConstraintMax:
+        if size, unit := valueSize(value); size > c.number {
+            return unit + " must be at most " + c.param
+        }
+    case ConstraintOneOf:
+        for _, v := range c.values {
+            if v == rawValue {
+                return ""
+            }
+        }
+        return "value must be one of [" + strings.Join(c.values, " ") + "]"
+    case ConstraintRegex:
+        if !c.regex.MatchString(rawValue) {
+            return "value must match " + c.param
+        }
+    }
+    return ""
+}
+
+func hasSize(kind reflect.Kind) bool {
+    switch kind {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+        reflect.Float32, reflect.Float64,
+        reflect.String:
+        return true
+    }
+    return false
+}
+
+// Returns the number compared by min/max constraints: the value itself
+// for numbers or the length for strings. Other kinds are rejected by validateFor.
+func valueSize(value reflect.Value) (float64, string) {
+    switch value.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return float64(value.Int()), "value"
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return float64(value.Uint()), "value"
+    case reflect.Float32, reflect.Float64:
+        return value.Float(), "value"
+    case reflect.String:
+        return float64(utf8.RuneCountInString(value.String())), "length"
+    }
+    return 0, "value"
+}
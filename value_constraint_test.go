@@ -0,0 +1,45 @@
+package pp_ioc
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestSizeConstraintsAreRejectedForUnsupportedKinds(t *testing.T) {
+    params := reflect.TypeOf(struct {
+        Port    int    `value:"${server.port}" validate:"min=1,max=65535"`
+        Name    string `value:"${app.name}" validate:"min=1"`
+        Debug   bool   `value:"${app.debug}" validate:"min=1"`
+        Verbose bool   `value:"${app.verbose}" validate:"required,max=0"`
+    }{})
+
+    for _, name := range []string{"Port", "Name"} {
+        field, _ := params.FieldByName(name)
+        if e := validateValue(field); e != nil {
+            t.Errorf("Expected valid constraints of %s, got %v", name, e)
+        }
+    }
+    for _, name := range []string{"Debug", "Verbose"} {
+        field, _ := params.FieldByName(name)
+        if e := validateValue(field); e == nil {
+            t.Errorf("Expected the size constraint of %s to be rejected", name)
+        }
+    }
+}
+
+func TestBuildFailsOnSizeConstraintOfBool(t *testing.T) {
+    ctx := NewContext()
+    ctx.NewBinder().
+        Qualifiers("server").
+        Factory(func(p struct {
+            Debug bool `value:"${app.debug:true}" validate:"max=0"`
+        }) *testServer {
+            return &testServer{}
+        })
+    if e := ctx.Build(); e == nil {
+        _ = ctx.Close()
+        t.Error("Expected the error of the max constraint on the bool field")
+    }
+}
+
+type testServer struct{}